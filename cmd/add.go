@@ -45,27 +45,29 @@ func addCommand() *cobra.Command {
 		),
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			cfg, err := configFile.Read()
+			name := args[0]
+			envs, err := config.ParseEnvFlagToEnv(flags.env)
 			if err != nil {
+				checkErrorAndPrintCommandExample(cmd, err)
 				return err
 			}
-
-			name := args[0]
 			profile := config.Profile{
 				Desc: flags.desc,
-				Env:  []*config.Env{},
+				Env:  envs,
 			}
-			profile.Env = config.ParseEnvFlagToEnv(flags.env)
-
-			// set profile
-			cfg.SetProfile(name, profile)
 
-			// set profile as default profile if default is empty and no profile is existing
-			if cfg.Default == "" {
-				cfg.SetDefault(name)
-			}
-
-			if err := configFile.Save(); err != nil {
+			err = configFile.Update(func(cfg *config.Config) error {
+				// set profile
+				if err := cfg.SetProfile(name, profile); err != nil {
+					return err
+				}
+				// set profile as default profile if default is empty and no profile is existing
+				if cfg.Default == "" {
+					cfg.SetDefault(name)
+				}
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
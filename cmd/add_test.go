@@ -84,6 +84,48 @@ var _ = Describe("Add Command", func() {
 		})
 	})
 
+	When("add profile with env vars that are not in sorted order", func() {
+		profileName := "unit-test-order"
+		envs := []string{"VAR_B=$VAR_A/b", "VAR_A=a"}
+		BeforeEach(func() {
+			args = append(args, profileName, "-e", envs[0], "-e", envs[1])
+		})
+
+		It("should keep the declaration order", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			p, err := cfg.Profile(profileName)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(p.Env.String()).Should(Equal(strings.Join(envs, ",")))
+		})
+	})
+
+	When("add profile with env value that contains =", func() {
+		profileName := "unit-test-equal"
+		BeforeEach(func() {
+			args = append(args, profileName, "-e", "TOKEN=abc==", "-e", "URL=http://x?a=b&c=d")
+		})
+
+		It("should keep the value after the first =", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+			p, err := cfg.Profile(profileName)
+			Expect(err).ShouldNot(HaveOccurred())
+			Expect(p.Env.String()).Should(Equal("TOKEN=abc==,URL=http://x?a=b&c=d"))
+		})
+	})
+
+	When("add profile with env flag that is not var=val format", func() {
+		profileName := "unit-test-invalid-env"
+		BeforeEach(func() {
+			args = append(args, profileName, "-e", "VAR=val", "-e", "not-valid")
+		})
+
+		It("should be error and profile should not be added", func() {
+			Expect(err).Should(HaveOccurred())
+			_, err := cfg.Profile(profileName)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+
 	When("add profile that is already existing", func() {
 		profileName := "lab.cluster1"
 		envs := []string{"env1=var1", "env2=var2"}
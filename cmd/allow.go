@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/trust"
+)
+
+func init() {
+	rootCmd.AddCommand(allowCommand())
+}
+
+// example of allow command
+func cmdExampleAllow() string {
+	return `
+  envp allow
+  envp allow profile-name
+  `
+}
+
+// allowCommand allows init-scripts and commands of profile to run
+func allowCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "allow profile-name",
+		Short:             "Allow init-scripts and commands of profile to run",
+		Long:              "Allow init-scripts, command substitutions and value-from commands of profile to run. profile needs to be allowed again when they are changed. all the env values are allowed as well for legacy expansion and profile from project config file",
+		SilenceUsage:      true,
+		ValidArgsFunction: validArgsProfileList,
+		Example:           cmdExampleAllow(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+			profile, err := currentResolvedProfile(cfg, args)
+			if err != nil {
+				checkErrorAndPrintCommandExample(cmd, err)
+				return err
+			}
+
+			store, err := trust.NewStoreFile(trustFileName)
+			if err != nil {
+				return err
+			}
+			if err := store.Allow(configFile.Name(), profile.Name, profileContent(cfg, profile)); err != nil {
+				return err
+			}
+			cmd.Println("Profile", profile.Name, "allowed successfully")
+			return nil
+		},
+	}
+	return cmd
+}
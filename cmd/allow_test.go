@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/shell"
+)
+
+var _ = Describe("Allow Command", Ordered, func() {
+
+	var (
+		stdout, stderr bytes.Buffer
+		testConfigFile = fmt.Sprintf("/tmp/allow-%v.yaml", GinkgoRandomSeed())
+	)
+
+	writeConfig := func(script string) {
+		os.WriteFile(testConfigFile, []byte(fmt.Sprintf(`default: scripted
+profiles:
+  scripted:
+    env:
+    - name: VAR
+      value: $(echo from-command)
+    init-script: %s
+`, script)), 0600)
+	}
+
+	BeforeAll(func() {
+		configFileName = testConfigFile
+		writeConfig("echo hello")
+		initConfig()
+		DeferCleanup(func() {
+			os.Remove(testConfigFile)
+		})
+	})
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	run := func(args ...string) error {
+		sc := shell.NewShellCommand()
+		sc.Stdout = &stdout
+		sc.Stderr = &stderr
+		cmd := rootCommand(sc)
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(append(args, "--", "env"))
+		return cmd.Execute()
+	}
+
+	allow := func() error {
+		cmd := allowCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs([]string{"scripted"})
+		return cmd.Execute()
+	}
+
+	It("should refuse to run the profile that is not allowed", func() {
+		err := run("scripted")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("envp allow scripted"))
+		Expect(err.Error()).To(ContainSubstring("+ init-script: echo hello"))
+		Expect(stdout.String()).NotTo(ContainSubstring("hello"))
+	})
+
+	It("should refuse the command substitution that is not allowed even if init-script is skipped", func() {
+		Expect(run("scripted", "--skip-init")).NotTo(Succeed())
+	})
+
+	It("should run the profile that is allowed", func() {
+		Expect(allow()).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("Profile scripted allowed"))
+		stdout.Reset()
+		Expect(run("scripted")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("VAR=from-command"))
+	})
+
+	It("should refuse to run the profile that is changed and show the diff", func() {
+		writeConfig("echo changed")
+		err := run("scripted")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("- init-script: echo hello\n+ init-script: echo changed"))
+	})
+
+	It("should run the changed profile without init-script if other commands are allowed", func() {
+		Expect(run("scripted", "--skip-init")).To(Succeed())
+	})
+})
+
+var _ = Describe("Allow Command with project layer", func() {
+
+	var stdout, stderr bytes.Buffer
+
+	run := func(args ...string) error {
+		sc := shell.NewShellCommand()
+		sc.Stdout = &stdout
+		sc.Stderr = &stderr
+		cmd := rootCommand(sc)
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(append(args, "--", "env"))
+		return cmd.Execute()
+	}
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+		dir := GinkgoT().TempDir()
+		configFileName = filepath.Join(dir, "config.yaml")
+		os.WriteFile(configFileName, []byte("version: 2\nprofiles:\n  tools:\n    env:\n    - name: VAR\n      value: user\n"), 0600)
+		project := filepath.Join(dir, "repo")
+		os.MkdirAll(project, 0755)
+		os.WriteFile(filepath.Join(project, ".envp.yaml"), []byte("profiles:\n  tools:\n    env:\n    - name: PATH\n      value: ./bin\n      op: prepend\n"), 0600)
+		GinkgoT().Chdir(project)
+		initConfig()
+	})
+
+	It("should refuse the static values of the profile from project config file until they are allowed", func() {
+		err := run("tools")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("+ env PATH=./bin"))
+
+		cmd := allowCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetArgs([]string{"tools"})
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(run("tools")).To(Succeed())
+	})
+})
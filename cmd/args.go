@@ -59,3 +59,16 @@ var validArgsProfileList = func(cmd *cobra.Command, args []string, toComplete st
 	cfg, _ := configFile.Read()
 	return cfg.ProfileNames(), cobra.ShellCompDirectiveNoFileComp
 }
+
+// validArgsBackupList is for auto complete of backup id
+var validArgsBackupList = func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	backups, _ := configFile.Target().Backups()
+	ids := make([]string, 0, len(backups))
+	for _, b := range backups {
+		ids = append(ids, b.ID)
+	}
+	return ids, cobra.ShellCompDirectiveNoFileComp
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/audit"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func init() {
+	rootCmd.AddCommand(auditCommand())
+}
+
+// example of audit command
+func cmdExampleAudit() string {
+	return `
+  # scan plaintext secrets in env values and init-scripts of all profiles
+  envp audit secrets
+  `
+}
+
+// auditCommand audits the config file
+func auditCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "audit",
+		Short:        "Audit the config file",
+		SilenceUsage: true,
+		Example:      cmdExampleAudit(),
+	}
+	cmd.AddCommand(auditSecretsCommand())
+	return cmd
+}
+
+// auditSecretsCommand reports the plaintext secrets in the config file. it returns error if any is found so that it can be used for pre-commit
+func auditSecretsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "secrets",
+		Short:        "Find plaintext secrets in env values and init-scripts",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+
+			var allowlist []config.AuditAllow
+			if cfg.Audit != nil {
+				allowlist = cfg.Audit.Allowlist
+			}
+			findings := audit.Secrets(cfg.Profiles, allowlist)
+			if len(findings) == 0 {
+				cmd.Println("No plaintext secret found")
+				return nil
+			}
+
+			// values are never printed
+			for _, f := range findings {
+				cmd.Printf("%s\t%s\t%s\n", color.RedString(f.Profile), f.Name, f.Rule)
+				cmd.Println("  fix:", f.Suggestion())
+			}
+			cmd.Println("")
+			cmd.Println("add the finding into audit.allowlist of config file if it is not secret")
+			return fmt.Errorf("%d plaintext secrets found", len(findings))
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/bundle"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+// flags struct for bundle sign command
+type bundleSignFlags struct {
+	key string
+}
+
+// flags struct for bundle verify command
+type bundleVerifyFlags struct {
+	publicKeys []string
+}
+
+func init() {
+	rootCmd.AddCommand(bundleCommand())
+}
+
+// example of bundle command
+func cmdExampleBundle() string {
+	return `
+  # generate key pair. team-key is private key and team-key.pub is public key
+  envp bundle keygen team-key
+
+  # sign the bundle file with private key
+  envp bundle sign team-profiles.yaml --key team-key
+
+  # verify the bundle file with trusted keys of config and public key
+  envp bundle verify team-profiles.yaml --public-key team-key.pub
+
+  # import profiles of the bundle file into config
+  envp bundle import team-profiles.yaml
+  `
+}
+
+// bundleCommand manages the signed profile bundles
+func bundleCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "bundle",
+		Short:        "Sign, verify and import profile bundles",
+		SilenceUsage: true,
+		Example:      cmdExampleBundle(),
+	}
+	cmd.AddCommand(bundleKeygenCommand())
+	cmd.AddCommand(bundleSignCommand())
+	cmd.AddCommand(bundleVerifyCommand())
+	cmd.AddCommand(bundleImportCommand())
+	return cmd
+}
+
+// bundleKeygenCommand generates ed25519 key pair to sign the bundles
+func bundleKeygenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "keygen key-file",
+		Short:        "Generate key pair to sign profile bundles",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pub, priv, err := bundle.GenerateKey()
+			if err != nil {
+				return err
+			}
+			if err := os.WriteFile(args[0], []byte(priv+"\n"), 0600); err != nil {
+				return err
+			}
+			if err := os.WriteFile(args[0]+".pub", []byte(pub+"\n"), 0644); err != nil {
+				return err
+			}
+			cmd.Println("Private key:", args[0])
+			cmd.Println("Public key:", args[0]+".pub")
+			cmd.Println("")
+			cmd.Println("add the public key into bundle.trusted-keys of config file to trust the bundles that are signed with the key")
+			cmd.Println("  - name: my-key")
+			cmd.Println("    public-key:", pub)
+			return nil
+		},
+	}
+	return cmd
+}
+
+// bundleSignCommand signs the bundle file with private key
+func bundleSignCommand() *cobra.Command {
+	var flags bundleSignFlags
+
+	cmd := &cobra.Command{
+		Use:          "sign bundle-file",
+		Short:        "Sign profile bundle with private key",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			priv, err := bundle.ReadPrivateKeyFile(flags.key)
+			if err != nil {
+				return err
+			}
+			b, err := bundle.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			if err := b.Sign(priv); err != nil {
+				return err
+			}
+			if err := b.WriteFile(args[0]); err != nil {
+				return err
+			}
+			cmd.Println("Bundle", args[0], "signed with key", b.Signature.KeyID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVarP(&flags.key, "key", "k", "", "private key file to sign the bundle")
+	cmd.MarkFlagRequired("key")
+	return cmd
+}
+
+// bundleVerifyCommand verifies the signature of bundle file with trusted keys
+func bundleVerifyCommand() *cobra.Command {
+	var flags bundleVerifyFlags
+
+	cmd := &cobra.Command{
+		Use:          "verify bundle-file",
+		Short:        "Verify signature of profile bundle with trusted keys",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+			keys := trustedKeys(cfg)
+			for _, k := range flags.publicKeys {
+				// public key can be given as file or base64 string
+				if b, err := os.ReadFile(k); err == nil {
+					keys = append(keys, config.TrustedKey{Name: k, PublicKey: string(b)})
+				} else {
+					keys = append(keys, config.TrustedKey{Name: "--public-key", PublicKey: k})
+				}
+			}
+
+			b, err := bundle.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			key, err := b.Verify(keys)
+			if err != nil {
+				return err
+			}
+			cmd.Println("Bundle", args[0], "is signed by", key.Name, "("+b.Signature.KeyID+")")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&flags.publicKeys, "public-key", []string{}, "public key file or base64 encoded public key to trust in addition to bundle.trusted-keys of config")
+	return cmd
+}
+
+// bundleImportCommand imports the profiles of bundle into config. top level profiles of bundle replace the profiles that have same name
+func bundleImportCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "import bundle-file",
+		Short:        "Import profiles of bundle into config",
+		Long:         "Import profiles of bundle into config. top level profiles of bundle replace the profiles that have same name. signature is required if bundle.require-signature of config is true",
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := bundle.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+
+			names := make([]string, 0, len(*b.Profiles))
+			for name := range *b.Profiles {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			// policy of bundle is merged from the layers so that the policy of system layer is applied to the import into other layers
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+			if err := verifyBundleForImport(cmd, cfg, b); err != nil {
+				return err
+			}
+
+			err = configFile.Update(func(cfg *config.Config) error {
+				for _, name := range names {
+					if err := cfg.SetProfile(name, *(*b.Profiles)[name]); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, name := range names {
+				cmd.Println("Profile", name, "imported")
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// verifyBundleForImport verifies the bundle with trusted keys of config.
+// bundle that is not verified can be imported with warning only if signature is not required and no trusted key is configured
+func verifyBundleForImport(cmd *cobra.Command, cfg *config.Config, b *bundle.Bundle) error {
+	keys := trustedKeys(cfg)
+	required := cfg.Bundle != nil && cfg.Bundle.RequireSignature
+
+	key, err := b.Verify(keys)
+	switch {
+	case err == nil:
+		cmd.Println("Bundle is signed by", key.Name, "("+b.Signature.KeyID+")")
+		return nil
+	case required:
+		return fmt.Errorf("signature is required: %w", err)
+	case b.Signature != nil && len(keys) > 0:
+		return err
+	}
+	cmd.Println(color.YellowString("WARN: %v. importing without verification", err))
+	return nil
+}
+
+// trustedKeys returns trusted keys of bundle signers in config
+func trustedKeys(cfg *config.Config) []config.TrustedKey {
+	if cfg.Bundle == nil {
+		return nil
+	}
+	return append([]config.TrustedKey{}, cfg.Bundle.TrustedKeys...)
+}
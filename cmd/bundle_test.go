@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bundle Command", Ordered, func() {
+
+	var (
+		stdout, stderr bytes.Buffer
+		dir            string
+		testConfigFile string
+		bundleFile     string
+		keyFile        string
+	)
+
+	run := func(args ...string) error {
+		cmd := bundleCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	writeConfig := func(bundleConfig string) {
+		os.WriteFile(testConfigFile, []byte("default: local\n"+bundleConfig+`profiles:
+  local:
+    env:
+    - name: LOCAL
+      value: local
+`), 0600)
+		initConfig()
+	}
+
+	BeforeAll(func() {
+		dir = GinkgoT().TempDir()
+		testConfigFile = filepath.Join(dir, "config.yaml")
+		bundleFile = filepath.Join(dir, "team.yaml")
+		keyFile = filepath.Join(dir, "team-key")
+		configFileName = testConfigFile
+		os.WriteFile(bundleFile, []byte(`version: v1
+profiles:
+  team:
+    env:
+    - name: HTTP_PROXY
+      value: http://proxy:3128
+`), 0644)
+	})
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	It("should generate key pair", func() {
+		writeConfig("")
+		Expect(run("keygen", keyFile)).To(Succeed())
+		Expect(keyFile).To(BeAnExistingFile())
+		Expect(keyFile + ".pub").To(BeAnExistingFile())
+	})
+
+	It("should refuse to import unsigned bundle when signature is required", func() {
+		writeConfig("bundle:\n  require-signature: true\n")
+		Expect(run("import", bundleFile)).NotTo(Succeed())
+		cfg, _ := configFile.Read()
+		_, err := cfg.Profile("team")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should refuse to import unsigned bundle when signature is required by system layer", func() {
+		system := filepath.Join(dir, "system.yaml")
+		os.WriteFile(system, []byte("bundle:\n  require-signature: true\nprofiles: {}\n"), 0600)
+		systemFileName = system
+		DeferCleanup(func() { systemFileName = "" })
+		writeConfig("bundle:\n  require-signature: false\n")
+
+		err := run("import", bundleFile)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("signature is required"))
+		cfg, _ := configFile.Read()
+		_, err = cfg.Profile("team")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should sign and verify the bundle", func() {
+		Expect(run("sign", bundleFile, "--key", keyFile)).To(Succeed())
+		stdout.Reset()
+		Expect(run("verify", bundleFile, "--public-key", keyFile+".pub")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("is signed by"))
+	})
+
+	It("should import the bundle that is signed by trusted key", func() {
+		pub, _ := os.ReadFile(keyFile + ".pub")
+		writeConfig(fmt.Sprintf("bundle:\n  require-signature: true\n  trusted-keys:\n  - name: team\n    public-key: %s\n", strings.TrimSpace(string(pub))))
+		Expect(run("import", bundleFile)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("Bundle is signed by team"))
+
+		initConfig()
+		cfg, _ := configFile.Read()
+		p, err := cfg.Profile("team")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Env.String()).To(Equal("HTTP_PROXY=http://proxy:3128"))
+		_, err = cfg.Profile("local")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should refuse to import tampered bundle", func() {
+		b, _ := os.ReadFile(bundleFile)
+		os.WriteFile(bundleFile, bytes.Replace(b, []byte("proxy:3128"), []byte("evil:3128"), 1), 0644)
+		Expect(run("verify", bundleFile, "--public-key", keyFile+".pub")).NotTo(Succeed())
+
+		pub, _ := os.ReadFile(keyFile + ".pub")
+		writeConfig(fmt.Sprintf("bundle:\n  trusted-keys:\n  - name: team\n    public-key: %s\n", strings.TrimSpace(string(pub))))
+		Expect(run("import", bundleFile)).NotTo(Succeed())
+	})
+})
@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(cacheCommand())
+}
+
+// example of cache command
+func cmdExampleCache() string {
+	return `
+  # list cached values
+  envp cache list
+
+  # clear all cached values
+  envp cache clear
+  `
+}
+
+// cacheCommand manages the cache of evaluated env values
+func cacheCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "cache",
+		Short:        "Manage the cache of evaluated env values",
+		SilenceUsage: true,
+		Example:      cmdExampleCache(),
+	}
+	cmd.AddCommand(cacheListCommand())
+	cmd.AddCommand(cacheClearCommand())
+	return cmd
+}
+
+// cacheListCommand prints out the cached env values. values are not printed since they may be credentials
+func cacheListCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "list",
+		Short:        "List cached env values",
+		Aliases:      []string{"ls"},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := newCacheFile()
+			if err != nil {
+				return err
+			}
+			entries, err := cf.List()
+			if err != nil {
+				return err
+			}
+			if len(entries) == 0 {
+				cmd.Println("No cached value")
+				return nil
+			}
+			for _, e := range entries {
+				cmd.Printf("%s\t%s\texpires in %s\n", e.Profile, e.Name, time.Until(e.ExpiresAt).Round(time.Second))
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// cacheClearCommand clears all the cached env values
+func cacheClearCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "clear",
+		Short:        "Clear all cached env values",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cf, err := newCacheFile()
+			if err != nil {
+				return err
+			}
+			if err := cf.Clear(); err != nil {
+				return err
+			}
+			cmd.Println("Cache cleared successfully")
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/shell"
+)
+
+var _ = Describe("Cache Command", Ordered, func() {
+
+	var (
+		stdout, stderr bytes.Buffer
+		testConfigFile = fmt.Sprintf("/tmp/cache-%v.yaml", GinkgoRandomSeed())
+	)
+
+	config := `default: cached
+profiles:
+  cached:
+    env:
+    - name: TOKEN
+      value: $(echo token-value)
+      cache: 15m
+    - name: NOT_CACHED
+      value: $(echo not-cached)
+`
+
+	BeforeAll(func() {
+		configFileName = testConfigFile
+		os.WriteFile(testConfigFile, []byte(config), 0600)
+		allowAllProfiles()
+
+		// run command to cache the value
+		sc := shell.NewShellCommand()
+		sc.Stdout = &stdout
+		sc.Stderr = &stderr
+		cmd := rootCommand(sc)
+		cmd.SetArgs([]string{"cached", "--", "env"})
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("TOKEN=token-value"))
+
+		DeferCleanup(func() {
+			os.Remove(testConfigFile)
+		})
+	})
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+	})
+
+	run := func(args ...string) error {
+		cmd := cacheCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	It("should list cached value without printing the value", func() {
+		Expect(run("list")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("cached\tTOKEN\texpires in"))
+		Expect(stdout.String()).NotTo(ContainSubstring("token-value"))
+		Expect(stdout.String()).NotTo(ContainSubstring("NOT_CACHED"))
+	})
+
+	It("should clear cached value", func() {
+		Expect(run("clear")).To(Succeed())
+		stdout.Reset()
+		Expect(run("list")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("No cached value"))
+	})
+})
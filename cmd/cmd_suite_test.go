@@ -1,6 +1,10 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -15,4 +19,25 @@ func TestCmd(t *testing.T) {
 // set configFileName as test data
 var _ = BeforeSuite(func() {
 	configFileName = "../testdata/config.yaml"
+	cacheFileName = fmt.Sprintf("/tmp/envp-cache-%v/cache.yaml", GinkgoRandomSeed())
+	trustFileName = fmt.Sprintf("/tmp/envp-trust-%v/trust.yaml", GinkgoRandomSeed())
+	// do not merge system config file of the machine
+	systemFileName = ""
+	DeferCleanup(func() {
+		os.RemoveAll(filepath.Dir(cacheFileName))
+		os.RemoveAll(filepath.Dir(trustFileName))
+	})
 })
+
+// allowAllProfiles allows all the profiles of current config file to run init-scripts and commands
+func allowAllProfiles() {
+	initConfig()
+	cfg, err := configFile.Read()
+	Expect(err).NotTo(HaveOccurred())
+	for _, name := range cfg.ProfileNames() {
+		cmd := allowCommand()
+		cmd.SetOut(io.Discard)
+		cmd.SetArgs([]string{name})
+		Expect(cmd.Execute()).To(Succeed())
+	}
+}
@@ -1,8 +1,15 @@
 package cmd
 
 import (
+	"strings"
+
 	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/cache"
 	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/secret"
+	"github.com/sunggun-yu/envp/internal/shell"
+	"github.com/sunggun-yu/envp/internal/trust"
+	"github.com/sunggun-yu/envp/internal/util"
 )
 
 // CurrentProfile is function that returns config.Profile
@@ -18,6 +25,80 @@ func currentProfile(cfg *config.Config, args []string) (profile *config.NamedPro
 	return profile, err
 }
 
+// currentResolvedProfile is same as currentProfile but returns the profile that env and init-script of parent groups are merged into.
+// it should be used for showing and running the profile. the result must not be used for updating the config.
+func currentResolvedProfile(cfg *config.Config, args []string) (profile *config.NamedProfile, err error) {
+	switch {
+	case len(args) > 0:
+		profile, err = cfg.ResolvedProfile(args[0])
+	default:
+		profile, err = cfg.ResolvedDefaultProfile()
+	}
+	return profile, err
+}
+
+// configureShellCommand sets the settings of config and runtime flags into the shell command
+func configureShellCommand(sh *shell.ShellCommand, cfg *config.Config, env []string, debugEnv bool) error {
+	valueCache, err := newCacheFile()
+	if err != nil {
+		return err
+	}
+	sh.Cache = valueCache
+	sh.Secrets = secret.NewCipher(secretKeySource("Passphrase", false))
+	overrides, err := config.ParseEnvFlagToEnv(env)
+	if err != nil {
+		return err
+	}
+	sh.Overrides = overrides
+	sh.DebugEnv = debugEnv
+	sh.LegacyExpansion = cfg.LegacyExpansion()
+	sh.ValueTimeout = cfg.ValueTimeout
+	sh.TotalTimeout = cfg.TotalTimeout
+	return nil
+}
+
+// newCacheFile creates CacheFile for current config file
+func newCacheFile() (*cache.CacheFile, error) {
+	hash, err := configFile.Hash()
+	if err != nil {
+		return nil, err
+	}
+	return cache.NewCacheFile(cacheFileName, hash)
+}
+
+// checkProfileAllowed checks if the scripts and commands of resolved profile are allowed with `envp allow`.
+// init-scripts are not checked if they are skipped
+func checkProfileAllowed(cfg *config.Config, profile *config.NamedProfile, skipInitScript bool) error {
+	store, err := trust.NewStoreFile(trustFileName)
+	if err != nil {
+		return err
+	}
+	return store.Check(configFile.Name(), profile.Name, profileContent(cfg, profile), skipInitScript)
+}
+
+// profileContent returns the content of resolved profile to be allowed. all the env values are included
+// if they are evaluated by shell with legacy expansion, or the profile has fields from the project config file
+func profileContent(cfg *config.Config, profile *config.NamedProfile) trust.ProfileContent {
+	return trust.Content(profile.Profile, cfg.LegacyExpansion() || cfg.FromProject(profile.Name))
+}
+
+// saveConfig saves the config that is read from the config file of write layer.
+// it refuses to overwrite the change of other process unless --force is set
+func saveConfig(cfg *config.Config) error {
+	if forceSave {
+		return configFile.Target().ForceSave(cfg)
+	}
+	return configFile.Target().Save(cfg)
+}
+
+// configDiff returns the diff between the contents of config file. values of secret env vars are masked
+func configDiff(old, new []byte) string {
+	return util.Diff(
+		strings.TrimSuffix(string(config.MaskSecrets(old)), "\n"),
+		strings.TrimSuffix(string(config.MaskSecrets(new)), "\n"),
+	)
+}
+
 // print command example
 func printExample(cmd *cobra.Command) {
 	cmd.Println("Example:")
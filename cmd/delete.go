@@ -5,6 +5,7 @@ import (
 
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
 	"github.com/sunggun-yu/envp/internal/prompt"
 )
 
@@ -41,9 +42,8 @@ func deleteCommand() *cobra.Command {
 				return err
 			}
 
-			// set default="" if default profile is being deleted
+			// warn if default profile is being deleted
 			if profile.IsDefault {
-				cfg.SetDefault("")
 				cmd.Println(color.YellowString("WARN: You are deleting default profile '%s'. please set default profile once it is deleted", profile.Name))
 			}
 			// ask y/n decision before proceed delete
@@ -51,14 +51,18 @@ func deleteCommand() *cobra.Command {
 			// set cmd's  in or stdin into prompt
 			prompt.SetIn(cmd.InOrStdin())
 			// prompt.SetOut(cmd.OutOrStdout())
-			if prompt.Prompt() {
-				// delete profile
-				cfg.DeleteProfile(profile.Name)
-			} else {
+			if !prompt.Prompt() {
 				cmd.Println("Cancelled")
 				return nil
 			}
-			if err := configFile.Save(); err != nil {
+			err = configFile.Update(func(cfg *config.Config) error {
+				// set default="" if default profile is being deleted
+				if cfg.Default == profile.Name {
+					cfg.SetDefault("")
+				}
+				return cfg.DeleteProfile(profile.Name)
+			})
+			if err != nil {
 				return err
 			}
 			cmd.Println("Profile", profile.Name, "deleted successfully")
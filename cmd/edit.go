@@ -40,39 +40,47 @@ func editCommand() *cobra.Command {
 		ValidArgsFunction: validArgsProfileList,
 		RunE: func(cmd *cobra.Command, args []string) error {
 
-			cfg, err := configFile.Read()
-			if err != nil {
-				return err
-			}
-			profile, err := currentProfile(cfg, args)
+			envs, err := config.ParseEnvFlagToEnv(flags.env)
 			if err != nil {
 				checkErrorAndPrintCommandExample(cmd, err)
 				return err
 			}
 
-			// update desc if input is not empty
-			if flags.desc != "" {
-				profile.Desc = flags.desc
-			}
+			var name string
+			err = configFile.Update(func(cfg *config.Config) error {
+				profile, err := currentProfile(cfg, args)
+				if err != nil {
+					checkErrorAndPrintCommandExample(cmd, err)
+					return err
+				}
+				name = profile.Name
 
-			// update env
-			// parse flag.env into a map for easy checking
-			menv := config.ParseEnvFlagToMap(flags.env)
-			if menv != nil {
-				// loop profile.Env and check if flag.env has updated value(exist)
-				for _, e := range profile.Env {
-					if _, exist := menv[e.Name]; !exist {
-						menv[e.Name] = e.Value
-					}
+				// update desc if input is not empty
+				if flags.desc != "" {
+					profile.Desc = flags.desc
 				}
-				profile.Env = config.MapToEnv(menv)
-			}
 
-			if err := configFile.Save(); err != nil {
+				// update env
+				// value of existing env is updated with keeping op and separator. new env vars are appended in order of flags
+				for _, f := range envs {
+					updated := false
+					for _, e := range profile.Env {
+						if e.Name == f.Name {
+							e.Value = f.Value
+							updated = true
+						}
+					}
+					if !updated {
+						profile.Env = append(profile.Env, f)
+					}
+				}
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
-			cmd.Println("Profile", profile.Name, "updated successfully")
+			cmd.Println("Profile", name, "updated successfully")
 
 			return nil
 		},
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(fmtCommand())
+}
+
+// flags struct for fmt command
+type fmtFlags struct {
+	check bool
+	diff  bool
+}
+
+// example of fmt command
+func cmdExampleFmt() string {
+	return `
+  # rewrite config file in canonical format. comments are kept
+  envp fmt
+
+  # print the changes without writing config file
+  envp fmt --diff
+
+  # exit with error if config file is not formatted. e.g. for CI
+  envp fmt --check
+  `
+}
+
+// fmtCommand rewrites the config file in canonical format
+func fmtCommand() *cobra.Command {
+	var flags fmtFlags
+
+	cmd := &cobra.Command{
+		Use:          "fmt",
+		Short:        "Format config file",
+		SilenceUsage: true,
+		Example:      cmdExampleFmt(),
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// config file of write layer is formatted
+			target := configFile.Target()
+			cfg, err := target.Read()
+			if err != nil {
+				return err
+			}
+			current, err := os.ReadFile(target.Name())
+			if err != nil {
+				return err
+			}
+			formatted, err := cfg.Format()
+			if err != nil {
+				return err
+			}
+			changed := !bytes.Equal(current, formatted)
+
+			if flags.diff && changed {
+				cmd.Print(configDiff(current, formatted))
+			}
+			if flags.check {
+				if changed {
+					return fmt.Errorf("config file is not formatted. run envp fmt to format it")
+				}
+				return nil
+			}
+			if flags.diff {
+				return nil
+			}
+
+			if !changed {
+				cmd.Println("Config file is already formatted")
+				return nil
+			}
+			if err := saveConfig(cfg); err != nil {
+				return err
+			}
+			cmd.Println("Config file is formatted")
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.check, "check", false, "exit with error if config file is not formatted")
+	cmd.Flags().BoolVar(&flags.diff, "diff", false, "print the changes without writing config file")
+	return cmd
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Fmt Command", func() {
+	var (
+		stdout   bytes.Buffer
+		original []byte
+		args     []string
+		err      error
+	)
+
+	formatted := "version: 2\ndefault: a\nprofiles:\n  a:\n    env:\n    - name: VAR\n      value: VAL # comment\n  b:\n    env:\n    - name: VAR\n      value: VAL\n"
+
+	BeforeEach(func() {
+		stdout.Reset()
+		args = []string{}
+		configFileName = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		original = []byte("version: 2\nprofiles:\n    b:\n        env:\n            - name: VAR\n              value: \"VAL\"\n    a:\n        env:\n            - value: VAL # comment\n              name: VAR\ndefault: a\n")
+	})
+
+	JustBeforeEach(func() {
+		os.WriteFile(configFileName, original, 0600)
+		initConfig()
+		cmd := fmtCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stdout)
+		cmd.SetArgs(args)
+		err = cmd.Execute()
+	})
+
+	When("format", func() {
+		It("should rewrite config file canonically", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("Config file is formatted"))
+			b, _ := os.ReadFile(configFile.Name())
+			Expect(string(b)).To(Equal(formatted))
+		})
+	})
+
+	When("config file is already formatted", func() {
+		BeforeEach(func() {
+			original = []byte(formatted)
+		})
+
+		It("should do nothing", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("already formatted"))
+		})
+
+		It("should pass the check", func() {
+			Expect(fmtCheck()).To(Succeed())
+		})
+	})
+
+	When("diff", func() {
+		BeforeEach(func() {
+			args = append(args, "--diff")
+		})
+
+		It("should print the changes without writing config file", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("+   a:\n"))
+			b, _ := os.ReadFile(configFile.Name())
+			Expect(b).To(Equal(original))
+		})
+	})
+
+	When("check", func() {
+		BeforeEach(func() {
+			args = append(args, "--check")
+		})
+
+		It("should return error without writing config file", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("not formatted"))
+			b, _ := os.ReadFile(configFile.Name())
+			Expect(b).To(Equal(original))
+		})
+	})
+})
+
+// fmtCheck runs fmt command with --check
+func fmtCheck() error {
+	cmd := fmtCommand()
+	cmd.SetOut(&bytes.Buffer{})
+	cmd.SetErr(&bytes.Buffer{})
+	cmd.SetArgs([]string{"--check"})
+	return cmd.Execute()
+}
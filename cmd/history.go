@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func init() {
+	rootCmd.AddCommand(historyCommand())
+}
+
+// example of history command
+func cmdExampleHistory() string {
+	return `
+  # list backups of config file with summary of the changes made after each backup
+  envp history
+  `
+}
+
+// historyCommand lists the backups of config file that are taken before the config file is changed
+func historyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "history",
+		Short:        "List backups of config file",
+		SilenceUsage: true,
+		Example:      cmdExampleHistory(),
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// backups of config file of write layer
+			target := configFile.Target()
+			backups, err := target.Backups()
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				cmd.Println("No backup")
+				return nil
+			}
+
+			// changes after the backup are the changes from the backup to next newer one. latest backup is compared with current config
+			newer, err := os.ReadFile(target.Name())
+			if err != nil {
+				return err
+			}
+			for _, b := range backups {
+				content, err := target.ReadBackup(b.ID)
+				if err != nil {
+					return err
+				}
+				cmd.Printf("%s\t%s\t%s\n", b.ID, b.Time.Local().Format("2006-01-02 15:04:05"), changeSummary(content, newer))
+				newer = content
+			}
+			return nil
+		},
+	}
+	return cmd
+}
+
+// changeSummary returns one line summary of the changes from old to new content of config file
+func changeSummary(old, new []byte) string {
+	oldCfg, err := config.ParseConfig(old)
+	if err != nil {
+		return "unknown changes"
+	}
+	newCfg, err := config.ParseConfig(new)
+	if err != nil {
+		return "unknown changes"
+	}
+	return config.Summary(oldCfg, newCfg)
+}
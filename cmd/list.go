@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 )
@@ -35,11 +37,16 @@ func listCommand() *cobra.Command {
 
 			// print profiles.
 			for _, p := range cfg.ProfileNames() {
+				name := p
+				// file that the profile came from is shown if config is merged from the layers
+				if source := cfg.Source(p); source != "" {
+					name = fmt.Sprintf("%s (%s)", p, source)
+				}
 				if p == cfg.Default {
 					// mark default profile with * and green
-					cmd.Println(color.GreenString("* %s", p))
+					cmd.Println(color.GreenString("* %s", name))
 				} else {
-					cmd.Println(" ", p)
+					cmd.Println(" ", name)
 				}
 			}
 			return nil
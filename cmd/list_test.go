@@ -3,6 +3,8 @@ package cmd
 import (
 	"bytes"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -34,3 +36,40 @@ var _ = Describe("List", func() {
 		})
 	})
 })
+
+var _ = Describe("List with layers", func() {
+	var stdout bytes.Buffer
+
+	BeforeEach(func() {
+		stdout.Reset()
+		dir := GinkgoT().TempDir()
+		configFileName = filepath.Join(dir, "config.yaml")
+		os.WriteFile(configFileName, []byte("version: 2\ndefault: mine\nprofiles:\n  mine:\n    env:\n    - name: VAR\n      value: user\n"), 0600)
+		project := filepath.Join(dir, "repo")
+		os.MkdirAll(filepath.Join(project, "sub"), 0755)
+		os.WriteFile(filepath.Join(project, ".envp.yaml"), []byte("profiles:\n  repo:\n    env:\n    - name: VAR\n      value: repo\n"), 0600)
+		GinkgoT().Chdir(filepath.Join(project, "sub"))
+		initConfig()
+	})
+
+	It("should list the profiles of all the layers with the file that they came from", func() {
+		cmd := listCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stdout)
+		cmd.SetArgs([]string{})
+		Expect(cmd.Execute()).To(Succeed())
+		wd, _ := os.Getwd()
+		Expect(stdout.String()).To(ContainSubstring("* mine (" + configFileName + ")"))
+		Expect(stdout.String()).To(ContainSubstring("  repo (" + filepath.Join(filepath.Dir(wd), ".envp.yaml") + ")"))
+	})
+
+	It("should show the file that the profile came from", func() {
+		cmd := showCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stdout)
+		cmd.SetArgs([]string{"repo"})
+		Expect(cmd.Execute()).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("# source: "))
+		Expect(stdout.String()).To(ContainSubstring(".envp.yaml"))
+	})
+})
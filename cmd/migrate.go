@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func init() {
+	rootCmd.AddCommand(migrateCommand())
+}
+
+// flags struct for migrate command
+type migrateFlags struct {
+	dryRun bool
+}
+
+// example of migrate command
+func cmdExampleMigrate() string {
+	return `
+  # preview the changes of migration
+  envp migrate --dry-run
+
+  # upgrade config file to the latest version. original config file is backed up
+  envp migrate
+  `
+}
+
+// migrateCommand upgrades the config file to the latest version
+func migrateCommand() *cobra.Command {
+	var flags migrateFlags
+
+	cmd := &cobra.Command{
+		Use:          "migrate",
+		Short:        "Upgrade config file to the latest version",
+		SilenceUsage: true,
+		Example:      cmdExampleMigrate(),
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// config file of write layer is migrated
+			target := configFile.Target()
+			// config is migrated when it is read
+			cfg, err := target.Read()
+			if err != nil {
+				return err
+			}
+			if len(cfg.Migrations()) == 0 {
+				cmd.Println("Config file is already version", config.CurrentVersion)
+				return nil
+			}
+			for _, m := range cfg.Migrations() {
+				cmd.Printf("version %d -> %d: %s\n", m.From, m.From+1, m.Description)
+			}
+
+			if flags.dryRun {
+				current, err := os.ReadFile(target.Name())
+				if err != nil {
+					return err
+				}
+				migrated, err := config.Marshal(cfg)
+				if err != nil {
+					return err
+				}
+				cmd.Print(configDiff(current, migrated))
+				return nil
+			}
+
+			if err := saveConfig(cfg); err != nil {
+				return err
+			}
+			cmd.Println("Config file is migrated to version", config.CurrentVersion)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&flags.dryRun, "dry-run", false, "print the changes without writing config file")
+	return cmd
+}
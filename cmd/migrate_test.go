@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrate Command", func() {
+	var (
+		stdout   bytes.Buffer
+		original []byte
+		args     []string
+		err      error
+	)
+
+	BeforeEach(func() {
+		stdout.Reset()
+		args = []string{}
+		configFileName = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		original = []byte("default: a\nprofiles:\n  a:\n    env:\n    - name: VAR\n      value: VAL\n    init-script: echo meow\n")
+		os.WriteFile(configFileName, original, 0600)
+	})
+
+	JustBeforeEach(func() {
+		initConfig()
+		cmd := migrateCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stdout)
+		cmd.SetArgs(args)
+		err = cmd.Execute()
+	})
+
+	When("dry-run", func() {
+		BeforeEach(func() {
+			args = append(args, "--dry-run")
+		})
+
+		It("should print the changes without writing config file", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("version 1 -> 2"))
+			Expect(stdout.String()).To(ContainSubstring("+ version: 2"))
+			Expect(stdout.String()).To(ContainSubstring("-     init-script: echo meow"))
+			b, _ := os.ReadFile(configFile.Name())
+			Expect(b).To(Equal(original))
+		})
+	})
+
+	When("migrate", func() {
+		It("should write migrated config file and back up the original", func() {
+			Expect(err).NotTo(HaveOccurred())
+			b, _ := os.ReadFile(configFile.Name())
+			Expect(string(b)).To(ContainSubstring("version: 2"))
+			Expect(string(b)).To(ContainSubstring("- run: echo meow"))
+
+			backups, _ := configFile.Backups()
+			Expect(backups).To(HaveLen(1))
+			backup, _ := configFile.ReadBackup(backups[0].ID)
+			Expect(backup).To(Equal(original))
+		})
+	})
+
+	When("config file is already the latest version", func() {
+		BeforeEach(func() {
+			os.WriteFile(configFileName, []byte("version: 2\ndefault: \"\"\nprofiles: {}\n"), 0600)
+		})
+
+		It("should do nothing", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("already version 2"))
+		})
+	})
+})
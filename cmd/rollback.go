@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/prompt"
+)
+
+func init() {
+	rootCmd.AddCommand(rollbackCommand())
+}
+
+// example of rollback command
+func cmdExampleRollback() string {
+	return `
+  # restore config file from the latest backup
+  envp rollback
+
+  # restore config file from the backup of id. check the id with 'envp history'
+  envp rollback 20240102T150405.000000000Z
+  `
+}
+
+// rollbackCommand restores the config file from the backup after showing the diff
+func rollbackCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:               "rollback [backup-id]",
+		Short:             "Restore config file from backup",
+		SilenceUsage:      true,
+		Example:           cmdExampleRollback(),
+		Args:              cobra.MaximumNArgs(1),
+		ValidArgsFunction: validArgsBackupList,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// config file of write layer is rolled back
+			target := configFile.Target()
+			backups, err := target.Backups()
+			if err != nil {
+				return err
+			}
+			if len(backups) == 0 {
+				return fmt.Errorf("no backup to rollback")
+			}
+
+			// latest backup is restored if id is not specified
+			id := backups[0].ID
+			if len(args) > 0 {
+				id = args[0]
+			}
+			backup, err := target.ReadBackup(id)
+			if err != nil {
+				return err
+			}
+			current, err := os.ReadFile(target.Name())
+			if err != nil {
+				return err
+			}
+			if bytes.Equal(current, backup) {
+				cmd.Println("Config file is same as backup", id)
+				return nil
+			}
+
+			cmd.Print(configDiff(current, backup))
+
+			// ask y/n decision before proceed rollback
+			prompt := prompt.NewPromptConfirm(fmt.Sprintf("Rollback config file to backup %s", color.YellowString(id)))
+			prompt.SetIn(cmd.InOrStdin())
+			if !prompt.Prompt() {
+				cmd.Println("Cancelled")
+				return nil
+			}
+
+			if err := target.Restore(id); err != nil {
+				return err
+			}
+			cmd.Println("Config file is rolled back to backup", id)
+			return nil
+		},
+	}
+	return cmd
+}
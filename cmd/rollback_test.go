@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("History and Rollback Command", func() {
+	var stdout, stdin bytes.Buffer
+
+	addProfile := func(name string) {
+		Expect(configFile.Update(func(cfg *config.Config) error {
+			return cfg.SetProfile(name, config.Profile{Env: config.Envs{{Name: "VAR", Value: name}}})
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stdin.Reset()
+		configFileName = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		initConfig()
+	})
+
+	When("config file has no backup", func() {
+		It("should print no backup and fail to rollback", func() {
+			cmd := historyCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetArgs([]string{})
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("No backup"))
+
+			cmd = rollbackCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stdout)
+			cmd.SetArgs([]string{})
+			Expect(cmd.Execute()).To(HaveOccurred())
+		})
+	})
+
+	When("config file is changed", func() {
+		BeforeEach(func() {
+			addProfile("a")
+			addProfile("b")
+		})
+
+		It("should list the backups with summary", func() {
+			cmd := historyCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetArgs([]string{})
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stdout.String()).To(MatchRegexp(`(?m)^\S+\t\S+ \S+\tadded b\n\S+\t\S+ \S+\tadded a\n$`))
+		})
+
+		It("should rollback to the latest backup with diff when it is confirmed", func() {
+			cmd := rollbackCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetIn(&stdin)
+			cmd.SetArgs([]string{})
+			stdin.WriteString("y\n")
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("-     b:"))
+			Expect(stdout.String()).To(ContainSubstring("rolled back"))
+
+			cfg, _ := configFile.Read()
+			Expect(cfg.ProfileNames()).To(Equal([]string{"a"}))
+		})
+
+		It("should mask the values of secret env vars in diff", func() {
+			Expect(configFile.Update(func(cfg *config.Config) error {
+				return cfg.SetProfile("c", config.Profile{Env: config.Envs{{Name: "TOKEN", Value: "plaintext-token", Secret: true}}})
+			})).To(Succeed())
+			cmd := rollbackCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetIn(&stdin)
+			cmd.SetArgs([]string{})
+			stdin.WriteString("n\n")
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("-     c:"))
+			Expect(stdout.String()).To(ContainSubstring(config.MaskedValue))
+			Expect(stdout.String()).NotTo(ContainSubstring("plaintext-token"))
+		})
+
+		It("should not rollback when it is not confirmed", func() {
+			backups, _ := configFile.Backups()
+			cmd := rollbackCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetIn(&stdin)
+			cmd.SetArgs([]string{backups[1].ID})
+			stdin.WriteString("n\n")
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stdout.String()).To(ContainSubstring("Cancelled"))
+
+			cfg, _ := configFile.Read()
+			Expect(cfg.ProfileNames()).To(Equal([]string{"a", "b"}))
+		})
+	})
+})
@@ -3,31 +3,93 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/spf13/cobra"
 	"github.com/sunggun-yu/envp/internal/config"
 	"github.com/sunggun-yu/envp/internal/shell"
+	"github.com/sunggun-yu/envp/internal/util"
 )
 
+// configFileEnv is the env var of config file path. --config flag takes precedence over it
+const configFileEnv = "ENVP_CONFIG"
+
 var (
 	configFile     *config.ConfigFile                     // ConfigFile instance that is shared across the sub-commands
-	configFileName = "$HOME/.config/envp/config.yaml"     // config file path
+	configFileName string                                 // config file path. config.yaml in XDG config dir if it is not set by --config flag or ENVP_CONFIG
+	cacheFileName  string                                 // cache file path of evaluated env values. cache.yaml in XDG cache dir if it is not set
+	trustFileName  string                                 // trust store file path of allowed profiles. trust.yaml in XDG state dir if it is not set
+	systemFileName = config.SystemConfigFile              // config file of system layer
+	writeLayer     string                                 // layer of config file that changes are written to
+	forceSave      bool                                   // overwrite config file even if it is changed by other process
 	rootCmd        = rootCommand(shell.NewShellCommand()) // root command with default setup of shell command
 )
 
 // init
 func init() {
 	cobra.OnInitialize(initConfig)
+	rootCmd.PersistentFlags().StringVar(&configFileName, "config", "", fmt.Sprintf("config file path. default is $%s or config.yaml in $XDG_CONFIG_HOME/envp (~/.config/envp)", configFileEnv))
+	rootCmd.PersistentFlags().StringVar(&writeLayer, "layer", config.LayerUser, fmt.Sprintf("layer of config file that changes are written to. one of %s, %s, %s", config.LayerSystem, config.LayerUser, config.LayerProject))
+	rootCmd.PersistentFlags().BoolVar(&forceSave, "force", false, "overwrite config file even if it is changed by other process since it was read")
 }
 
 // initConfig initialize the config file
 func initConfig() {
+	if err := initFileNames(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
 	if cfg, err := config.NewConfigFile(configFileName); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	} else {
 		configFile = cfg
 	}
+	if err := initLayers(); err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+}
+
+// initLayers sets the config files of system and project layers that are merged with the config file,
+// and the layer that changes are written to. project layer is found by walking up from current directory
+func initLayers() error {
+	wd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	configFile.SetLayers(systemFileName, config.FindProjectConfigFile(wd))
+	return configFile.SetWriteLayer(writeLayer)
+}
+
+// initFileNames sets the file paths that are not set yet.
+// config file is chosen by --config flag, ENVP_CONFIG and XDG config dir in order
+func initFileNames() error {
+	if configFileName == "" {
+		configFileName = os.Getenv(configFileEnv)
+	}
+	if configFileName == "" {
+		dir, err := util.ConfigDir()
+		if err != nil {
+			return err
+		}
+		configFileName = filepath.Join(dir, "config.yaml")
+	}
+	if cacheFileName == "" {
+		dir, err := util.CacheDir()
+		if err != nil {
+			return err
+		}
+		cacheFileName = filepath.Join(dir, "cache.yaml")
+	}
+	if trustFileName == "" {
+		dir, err := util.StateDir()
+		if err != nil {
+			return err
+		}
+		trustFileName = filepath.Join(dir, "trust.yaml")
+	}
+	return nil
 }
 
 // Execute execute the root command and sub commands
@@ -56,12 +118,17 @@ func cmdExampleRoot() string {
 
 	# skip init-script
   envp profile-name --skip-init -- kubectl get namespaces
+
+  # override env var of profile at runtime
+  envp profile-name -e KUBECONFIG=~/.kube/other -- kubectl get namespaces
   `
 }
 
 // flags struct for start command
 type rootFlags struct {
 	skipInitScript bool
+	env            []string
+	debugEnv       bool
 }
 
 // rootCommand sets environment variable and execute command line
@@ -109,10 +176,10 @@ func rootCommand(sh *shell.ShellCommand) *cobra.Command {
 			*/
 			switch {
 			case cmd.ArgsLenAtDash() == 0:
-				profile, err = cfg.DefaultProfile()
+				profile, err = cfg.ResolvedDefaultProfile()
 				command = args
 			case cmd.ArgsLenAtDash() > 0:
-				profile, err = cfg.Profile(args[0])
+				profile, err = cfg.ResolvedProfile(args[0])
 				// only args after double dash "--"" should be considered as command
 				command = args[cmd.ArgsLenAtDash():]
 			}
@@ -121,6 +188,16 @@ func rootCommand(sh *shell.ShellCommand) *cobra.Command {
 				return err
 			}
 
+			// refuse to run the scripts and commands of profile that user didn't allow
+			if err := checkProfileAllowed(cfg, profile, flags.skipInitScript); err != nil {
+				return err
+			}
+
+			// set config and runtime overrides to shell command
+			if err := configureShellCommand(sh, cfg, flags.env, flags.debugEnv); err != nil {
+				return err
+			}
+
 			// Execute command
 			if err := sh.Execute(command, profile, flags.skipInitScript); err != nil {
 				return err
@@ -130,6 +207,8 @@ func rootCommand(sh *shell.ShellCommand) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&flags.skipInitScript, "skip-init", "s", false, `Skip running initialization scripts from the profile's "init-script"`)
+	cmd.Flags().StringArrayVarP(&flags.env, "env", "e", []string{}, "'VAR=VAL' format of string that overrides env of the profile")
+	cmd.Flags().BoolVar(&flags.debugEnv, "debug-env", false, "print env vars of the command with the layer that each value came from")
 
 	return cmd
 }
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -134,6 +136,7 @@ var _ = Describe("Root Command", func() {
 		if copy {
 			original, _ := os.ReadFile("../testdata/config.yaml")
 			os.WriteFile(testConfigFile, original, 0644)
+			allowAllProfiles()
 		}
 
 		cmd.SetArgs(args)          // set the arg for each test case
@@ -165,6 +168,27 @@ var _ = Describe("Root Command", func() {
 		})
 	})
 
+	When("execute command with runtime override of env", func() {
+		profileName := "lab.cluster2"
+		BeforeEach(func() {
+			args = append(args, profileName, "-e", "KUBECONFIG=/tmp/overridden", "--debug-env", "--", "env")
+		})
+
+		It("should not be error", func() {
+			Expect(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should include overridden value instead of profile value", func() {
+			Expect(stdout.String()).Should(ContainSubstring("KUBECONFIG=/tmp/overridden"))
+			Expect(stdout.String()).ShouldNot(ContainSubstring("KUBECONFIG=/Users/meow/.kube/lab-cluster2"))
+		})
+
+		It("should print out the layer of env vars", func() {
+			Expect(stderr.String()).Should(ContainSubstring("[runtime] KUBECONFIG=/tmp/overridden"))
+			Expect(stderr.String()).Should(ContainSubstring("[profile] HTTPS_PROXY=http://192.168.1.20:443"))
+		})
+	})
+
 	When("execute command with valid inputs but no specify profile name", func() {
 		BeforeEach(func() {
 			args = append(args, "--", "env")
@@ -254,3 +278,89 @@ var _ = Describe("Root Command", func() {
 		})
 	})
 })
+
+var _ = Describe("Config file selection", func() {
+	var original, originalCache, originalTrust string
+
+	BeforeEach(func() {
+		original, originalCache, originalTrust = configFileName, cacheFileName, trustFileName
+		configFileName, cacheFileName, trustFileName = "", "", ""
+		xdg := GinkgoT().TempDir()
+		GinkgoT().Setenv("XDG_CONFIG_HOME", filepath.Join(xdg, "config"))
+		GinkgoT().Setenv("XDG_STATE_HOME", filepath.Join(xdg, "state"))
+		GinkgoT().Setenv("XDG_CACHE_HOME", filepath.Join(xdg, "cache"))
+		GinkgoT().Setenv(configFileEnv, "")
+		DeferCleanup(func() {
+			configFileName, cacheFileName, trustFileName = original, originalCache, originalTrust
+		})
+	})
+
+	When("nothing is set", func() {
+		It("should use the files in XDG directories", func() {
+			initConfig()
+			Expect(configFile.Name()).To(Equal(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "envp", "config.yaml")))
+			Expect(cacheFileName).To(Equal(filepath.Join(os.Getenv("XDG_CACHE_HOME"), "envp", "cache.yaml")))
+			Expect(trustFileName).To(Equal(filepath.Join(os.Getenv("XDG_STATE_HOME"), "envp", "trust.yaml")))
+		})
+	})
+
+	When("ENVP_CONFIG is set", func() {
+		It("should use it", func() {
+			file := filepath.Join(GinkgoT().TempDir(), "work.yaml")
+			GinkgoT().Setenv(configFileEnv, file)
+			initConfig()
+			Expect(configFile.Name()).To(Equal(file))
+		})
+	})
+
+	When("--config flag is set", func() {
+		It("should take precedence over ENVP_CONFIG", func() {
+			GinkgoT().Setenv(configFileEnv, filepath.Join(GinkgoT().TempDir(), "work.yaml"))
+			file := filepath.Join(GinkgoT().TempDir(), "lab.yaml")
+			Expect(rootCmd.PersistentFlags().Set("config", file)).To(Succeed())
+			DeferCleanup(func() {
+				rootCmd.PersistentFlags().Lookup("config").Changed = false
+			})
+			initConfig()
+			Expect(configFile.Name()).To(Equal(file))
+		})
+
+		It("should be absolute path if it is relative", func() {
+			dir := GinkgoT().TempDir()
+			GinkgoT().Chdir(dir)
+			configFileName = "lab.yaml"
+			initConfig()
+			wd, _ := os.Getwd()
+			Expect(configFile.Name()).To(Equal(filepath.Join(wd, "lab.yaml")))
+		})
+	})
+})
+
+var _ = Describe("Save config", func() {
+	var cfg *config.Config
+
+	BeforeEach(func() {
+		configFileName = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		os.WriteFile(configFileName, []byte("version: 2\nprofiles:\n  a:\n    env:\n    - name: VAR\n      value: a\n"), 0600)
+		initConfig()
+		cfg, _ = configFile.Read()
+		cfg.SetDefault("a")
+		// changed by other process
+		os.WriteFile(configFileName, []byte("version: 2\nprofiles:\n  b:\n    env:\n    - name: VAR\n      value: b\n"), 0600)
+		DeferCleanup(func() { forceSave = false })
+	})
+
+	It("should refuse to overwrite the change of other process", func() {
+		var changedErr *config.ConfigFileChangedError
+		err := saveConfig(cfg)
+		Expect(errors.As(err, &changedErr)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("--force"))
+	})
+
+	It("should overwrite the change of other process with --force", func() {
+		Expect(rootCmd.PersistentFlags().Set("force", "true")).To(Succeed())
+		Expect(saveConfig(cfg)).To(Succeed())
+		b, _ := os.ReadFile(configFileName)
+		Expect(string(b)).To(ContainSubstring("default: a"))
+	})
+})
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func init() {
+	rootCmd.AddCommand(schemaCommand())
+}
+
+// example of schema command
+func cmdExampleSchema() string {
+	return `
+  # print JSON Schema of config file
+  envp schema
+
+  # save it for the editor. e.g. add "# yaml-language-server: $schema=envp.schema.json" on top of config file
+  envp schema > ~/.config/envp/envp.schema.json
+  `
+}
+
+// schemaCommand prints JSON Schema of config file. validate command checks config file against the same schema
+func schemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "schema",
+		Short:        "Print JSON Schema of config file",
+		SilenceUsage: true,
+		Example:      cmdExampleSchema(),
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := json.MarshalIndent(config.Schema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			// print to stdout rather than stderr of cmd.Println so that it can be redirected to file
+			fmt.Fprintln(cmd.OutOrStdout(), string(b))
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("Schema", func() {
+	When("execute the schema command", func() {
+		It("should print JSON Schema of config file", func() {
+			var stdout, stderr bytes.Buffer
+			cmd := schemaCommand()
+			cmd.SetOut(&stdout)
+			cmd.SetErr(&stderr)
+			cmd.SetArgs([]string{})
+			Expect(cmd.Execute()).To(Succeed())
+			Expect(stderr.String()).To(BeEmpty())
+
+			var schema map[string]interface{}
+			Expect(json.Unmarshal(stdout.Bytes(), &schema)).To(Succeed())
+			Expect(schema["$id"]).To(Equal(config.SchemaID))
+			Expect(schema["$defs"]).To(HaveKey("Profile"))
+		})
+	})
+})
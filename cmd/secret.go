@@ -0,0 +1,274 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/prompt"
+	"github.com/sunggun-yu/envp/internal/secret"
+)
+
+// env vars for the key of encrypted values
+const (
+	secretKeyFileEnvVar       = "ENVP_SECRET_KEY_FILE"
+	secretPassphraseEnvVar    = "ENVP_SECRET_PASSPHRASE"
+	secretNewPassphraseEnvVar = "ENVP_SECRET_NEW_PASSPHRASE"
+)
+
+// flags struct for secret rekey command
+type secretRekeyFlags struct {
+	newKeyFile string
+}
+
+func init() {
+	rootCmd.AddCommand(secretCommand())
+}
+
+// example of secret command
+func cmdExampleSecret() string {
+	return `
+  # encrypt the value of env var of profile. value is prompted or read from stdin
+  envp secret set my-profile API_TOKEN
+  pbpaste | envp secret set my-profile API_TOKEN
+
+  # re-encrypt all the encrypted values with new passphrase
+  envp secret rekey
+
+  # re-encrypt all the encrypted values with new key file
+  envp secret rekey --new-key-file ~/.config/envp/new.key
+  `
+}
+
+// secretCommand manages the encrypted env values
+func secretCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "secret",
+		Short:        "Manage the encrypted env values",
+		SilenceUsage: true,
+		Example:      cmdExampleSecret(),
+	}
+	cmd.AddCommand(secretSetCommand())
+	cmd.AddCommand(secretRekeyCommand())
+	return cmd
+}
+
+// secretSetCommand encrypts the value and sets it into env var of profile
+func secretSetCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "set profile-name VAR",
+		Short:        "Encrypt the value of env var of profile",
+		SilenceUsage: true,
+		Args: cobra.MatchAll(
+			cobra.ExactArgs(2),
+			arg0NotExistingProfile(),
+		),
+		ValidArgsFunction: validArgsProfileList,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profileName, name := args[0], args[1]
+
+			value, err := readSecretValue(cmd)
+			if err != nil {
+				return err
+			}
+
+			// passphrase is confirmed for the first encrypted value since there is no existing value to check it against
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+			first := len(encryptedEnvs(cfg)) == 0
+
+			// encrypt before updating the config to not hold the config file while prompting passphrase
+			cipher := secret.NewCipher(secretKeySource("Passphrase", first))
+			encrypted, err := cipher.Encrypt(value)
+			if err != nil {
+				return err
+			}
+
+			err = configFile.Update(func(cfg *config.Config) error {
+				profile, err := cfg.Profile(profileName)
+				if err != nil {
+					return err
+				}
+				// ensure the key is same as the key of other encrypted values
+				if envs := encryptedEnvs(cfg); len(envs) > 0 {
+					if _, err := cipher.Decrypt(envs[0].Value); err != nil {
+						return fmt.Errorf("key is not same as the key of existing encrypted values: %w", err)
+					}
+				}
+
+				// value of existing env is updated with keeping op and separator
+				updated := false
+				for _, e := range profile.Env {
+					if e.Name == name {
+						e.Value = encrypted
+						e.ValueFrom = nil
+						updated = true
+					}
+				}
+				if !updated {
+					profile.Env = append(profile.Env, &config.Env{Name: name, Value: encrypted})
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Println("Secret", name, "of profile", profileName, "updated successfully")
+			return nil
+		},
+	}
+	return cmd
+}
+
+// secretRekeyCommand re-encrypts all the encrypted values with new key
+func secretRekeyCommand() *cobra.Command {
+	var flags secretRekeyFlags
+
+	cmd := &cobra.Command{
+		Use:          "rekey",
+		Short:        "Re-encrypt all the encrypted values with new key",
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg, err := configFile.Read()
+			if err != nil {
+				return err
+			}
+			envs := encryptedEnvs(cfg)
+			if len(envs) == 0 {
+				cmd.Println("No encrypted value")
+				return nil
+			}
+
+			// load the keys before updating the config to not hold the config file while prompting passphrases
+			oldCipher := secret.NewCipher(secretKeySource("Current passphrase", false))
+			if _, err := oldCipher.Decrypt(envs[0].Value); err != nil {
+				return fmt.Errorf("failed to decrypt %s: %w", envs[0].Name, err)
+			}
+			newCipher := secret.NewCipher(newSecretKeySource(flags.newKeyFile))
+			if _, err := newCipher.Encrypt(""); err != nil {
+				return err
+			}
+
+			count := 0
+			err = configFile.Update(func(cfg *config.Config) error {
+				envs := encryptedEnvs(cfg)
+				// decrypt all the values first. nothing is changed if any of them cannot be decrypted
+				values := make([]string, len(envs))
+				for i, e := range envs {
+					var err error
+					if values[i], err = oldCipher.Decrypt(e.Value); err != nil {
+						return fmt.Errorf("failed to decrypt %s: %w", e.Name, err)
+					}
+				}
+				for i, e := range envs {
+					var err error
+					if e.Value, err = newCipher.Encrypt(values[i]); err != nil {
+						return err
+					}
+				}
+				count = len(envs)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			cmd.Println(count, "encrypted values re-encrypted successfully")
+			cmd.Println("please use the new key from now on")
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&flags.newKeyFile, "new-key-file", "", "key file of new key. new passphrase is prompted if it is not set")
+	return cmd
+}
+
+// secretKeySource returns KeySource of the key of encrypted values.
+// key file of ENVP_SECRET_KEY_FILE, passphrase of ENVP_SECRET_PASSPHRASE and prompt of passphrase are used in order.
+// prompted passphrase is asked twice if confirm is true
+func secretKeySource(label string, confirm bool) secret.KeySource {
+	if name := os.Getenv(secretKeyFileEnvVar); name != "" {
+		return secret.KeyFile(os.ExpandEnv(name))
+	}
+	if passphrase := os.Getenv(secretPassphraseEnvVar); passphrase != "" {
+		return secret.Passphrase(passphrase)
+	}
+	return passphraseKeySource(label, confirm)
+}
+
+// newSecretKeySource returns KeySource of new key for rekey.
+// key file, passphrase of ENVP_SECRET_NEW_PASSPHRASE and prompt of new passphrase with confirmation are used in order
+func newSecretKeySource(keyFile string) secret.KeySource {
+	if keyFile != "" {
+		return secret.KeyFile(keyFile)
+	}
+	if passphrase := os.Getenv(secretNewPassphraseEnvVar); passphrase != "" {
+		return secret.Passphrase(passphrase)
+	}
+	return passphraseKeySource("New passphrase", true)
+}
+
+// passphraseKeySource returns KeySource that prompts the passphrase. it is prompted again to confirm if confirm is true
+func passphraseKeySource(label string, confirm bool) secret.KeySource {
+	return func() (string, error) {
+		passphrase, err := promptPassphrase(label)
+		if err != nil || !confirm {
+			return passphrase, err
+		}
+		again, err := promptPassphrase("Confirm " + strings.ToLower(label))
+		if err != nil {
+			return "", err
+		}
+		if passphrase != again {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+		return passphrase, nil
+	}
+}
+
+// promptPassphrase prompts the passphrase of label without echo
+var promptPassphrase = func(label string) (string, error) {
+	p := prompt.NewPromptPassword(label)
+	return p.Prompt()
+}
+
+// readSecretValue prompts the secret value if stdin is terminal. otherwise, it reads the value from stdin
+func readSecretValue(cmd *cobra.Command) (string, error) {
+	in := cmd.InOrStdin()
+	if f, ok := in.(*os.File); ok {
+		if fi, err := f.Stat(); err == nil && fi.Mode()&os.ModeCharDevice != 0 {
+			p := prompt.NewPromptPassword("Value")
+			return p.Prompt()
+		}
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return "", err
+	}
+	value := strings.TrimRight(string(b), "\r\n")
+	if value == "" {
+		return "", fmt.Errorf("value is empty")
+	}
+	return value, nil
+}
+
+// encryptedEnvs returns all the env vars that have encrypted value in the profiles of config
+func encryptedEnvs(cfg *config.Config) config.Envs {
+	envs := config.Envs{}
+	for _, name := range cfg.ProfileNames() {
+		p, err := cfg.Profile(name)
+		if err != nil {
+			continue
+		}
+		for _, e := range p.Env {
+			if secret.IsEncrypted(e.Value) {
+				envs = append(envs, e)
+			}
+		}
+	}
+	return envs
+}
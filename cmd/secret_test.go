@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/shell"
+)
+
+var _ = Describe("Secret Command", Ordered, func() {
+
+	var (
+		stdout, stderr bytes.Buffer
+		testConfigFile = fmt.Sprintf("/tmp/secret-%v.yaml", GinkgoRandomSeed())
+	)
+
+	config := `default: secret
+profiles:
+  secret:
+    env:
+    - name: PLAIN
+      value: plain
+`
+
+	BeforeAll(func() {
+		configFileName = testConfigFile
+		os.WriteFile(testConfigFile, []byte(config), 0600)
+		initConfig()
+		DeferCleanup(func() {
+			os.Remove(testConfigFile)
+		})
+	})
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+		GinkgoT().Setenv(secretPassphraseEnvVar, "old-passphrase")
+	})
+
+	run := func(in string, args ...string) error {
+		cmd := secretCommand()
+		cmd.SetIn(strings.NewReader(in))
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	runEnv := func() error {
+		sc := shell.NewShellCommand()
+		sc.Stdout = &stdout
+		sc.Stderr = &stderr
+		cmd := rootCommand(sc)
+		cmd.SetArgs([]string{"secret", "--", "env"})
+		return cmd.Execute()
+	}
+
+	It("should encrypt the value in the config file", func() {
+		Expect(run("my-token\n", "set", "secret", "TOKEN")).To(Succeed())
+		b, _ := os.ReadFile(testConfigFile)
+		Expect(string(b)).To(ContainSubstring("value: enc:v1:"))
+		Expect(string(b)).NotTo(ContainSubstring("my-token"))
+	})
+
+	It("should decrypt the value when running command", func() {
+		Expect(runEnv()).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("TOKEN=my-token\n"))
+	})
+
+	It("should not encrypt with the key that is different from existing values", func() {
+		GinkgoT().Setenv(secretPassphraseEnvVar, "other-passphrase")
+		Expect(run("other-token\n", "set", "secret", "OTHER")).NotTo(Succeed())
+	})
+
+	It("should re-encrypt the values with new key", func() {
+		GinkgoT().Setenv(secretNewPassphraseEnvVar, "new-passphrase")
+		Expect(run("", "rekey")).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("1 encrypted values re-encrypted"))
+
+		stdout.Reset()
+		Expect(runEnv()).NotTo(Succeed())
+
+		stdout.Reset()
+		GinkgoT().Setenv(secretPassphraseEnvVar, "new-passphrase")
+		Expect(runEnv()).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring("TOKEN=my-token\n"))
+	})
+})
+
+var _ = Describe("Secret Command with passphrase prompt", Ordered, func() {
+
+	var (
+		stdout, stderr bytes.Buffer
+		testConfigFile = fmt.Sprintf("/tmp/secret-prompt-%v.yaml", GinkgoRandomSeed())
+		labels         []string // labels of prompted passphrases
+		answers        []string // passphrases to answer in order
+	)
+
+	config := `default: secret
+profiles:
+  secret:
+    env:
+    - name: PLAIN
+      value: plain
+`
+
+	BeforeAll(func() {
+		configFileName = testConfigFile
+		os.WriteFile(testConfigFile, []byte(config), 0600)
+		initConfig()
+		original := promptPassphrase
+		promptPassphrase = func(label string) (string, error) {
+			labels = append(labels, label)
+			answer := answers[0]
+			answers = answers[1:]
+			return answer, nil
+		}
+		DeferCleanup(func() {
+			promptPassphrase = original
+			os.Remove(testConfigFile)
+		})
+	})
+
+	BeforeEach(func() {
+		stdout.Reset()
+		stderr.Reset()
+		labels = nil
+		GinkgoT().Setenv(secretKeyFileEnvVar, "")
+		GinkgoT().Setenv(secretPassphraseEnvVar, "")
+		GinkgoT().Setenv(secretNewPassphraseEnvVar, "")
+	})
+
+	run := func(in string, args ...string) error {
+		cmd := secretCommand()
+		cmd.SetIn(strings.NewReader(in))
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stderr)
+		cmd.SetArgs(args)
+		return cmd.Execute()
+	}
+
+	It("should not encrypt the first value when confirmation does not match", func() {
+		answers = []string{"passphrase", "typo"}
+		Expect(run("my-token\n", "set", "secret", "TOKEN")).NotTo(Succeed())
+		Expect(labels).To(Equal([]string{"Passphrase", "Confirm passphrase"}))
+		b, _ := os.ReadFile(testConfigFile)
+		Expect(string(b)).NotTo(ContainSubstring("enc:v1:"))
+	})
+
+	It("should ask passphrase twice for the first value", func() {
+		answers = []string{"passphrase", "passphrase"}
+		Expect(run("my-token\n", "set", "secret", "TOKEN")).To(Succeed())
+		Expect(labels).To(Equal([]string{"Passphrase", "Confirm passphrase"}))
+	})
+
+	It("should ask passphrase once when encrypted value is existing", func() {
+		answers = []string{"passphrase"}
+		Expect(run("other-token\n", "set", "secret", "OTHER")).To(Succeed())
+		Expect(labels).To(Equal([]string{"Passphrase"}))
+	})
+
+	It("should ask new passphrase twice when rekey", func() {
+		answers = []string{"passphrase", "new-passphrase", "typo"}
+		Expect(run("", "rekey")).NotTo(Succeed())
+		Expect(labels).To(Equal([]string{"Current passphrase", "New passphrase", "Confirm new passphrase"}))
+
+		labels = nil
+		answers = []string{"passphrase", "new-passphrase", "new-passphrase"}
+		Expect(run("", "rekey")).To(Succeed())
+		Expect(labels).To(Equal([]string{"Current passphrase", "New passphrase", "Confirm new passphrase"}))
+		Expect(stdout.String()).To(ContainSubstring("2 encrypted values re-encrypted"))
+	})
+})
@@ -3,12 +3,17 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/prompt"
+	"github.com/sunggun-yu/envp/internal/secret"
 )
 
 // flags struct for show command
 type showFlags struct {
 	export bool
+	reveal bool
 }
 
 func init() {
@@ -22,6 +27,7 @@ func cmdExampleShow() string {
   envp show profile-name
   envp show --export
   envp show -e
+  envp show --reveal
   `
 }
 
@@ -41,13 +47,28 @@ func showCommand() *cobra.Command {
 			if err != nil {
 				return err
 			}
-			profile, err := currentProfile(cfg, args)
+			profile, err := currentResolvedProfile(cfg, args)
 			if err != nil {
 				checkErrorAndPrintCommandExample(cmd, err)
 				return err
 			}
 
+			// reveal secret values only when it is confirmed
+			var cipher *secret.Cipher
+			if flags.reveal && hasSecret(profile.Env) {
+				prompt := prompt.NewPromptConfirm(fmt.Sprintf("Reveal secret values of profile %s", color.RedString(profile.Name)))
+				prompt.SetIn(cmd.InOrStdin())
+				if !prompt.Prompt() {
+					cmd.Println("Cancelled")
+					return nil
+				}
+				cipher = secret.NewCipher(secretKeySource("Passphrase", false))
+			}
+
 			cmd.Println("# profile:", profile.Name)
+			if source := cfg.Source(profile.Name); source != "" {
+				cmd.Println("# source:", source)
+			}
 			if flags.export {
 				cmd.Println("# you can export env vars of profile with following command")
 				cmd.Println("# eval $(envp show --export)")
@@ -55,15 +76,50 @@ func showCommand() *cobra.Command {
 				cmd.Println("")
 			}
 			for _, e := range profile.Env {
-				if flags.export {
+				// value-from is resolved only when running command. print its source as comment
+				if e.ValueFrom != nil {
+					cmd.Println("#", e.Name, "from", e.ValueFrom.String())
+					continue
+				}
+				if flags.export && e.Operation() != config.EnvOpUnset {
 					cmd.Print("export ")
 				}
-				cmd.Println(fmt.Sprint(e.Name, "=", e.Value))
+				statement := e.Statement()
+				if cipher != nil && e.IsSecret() {
+					if statement, err = revealedStatement(e, cipher); err != nil {
+						return err
+					}
+				}
+				cmd.Println(statement)
 			}
 			return nil
 		},
 	}
 
 	cmd.Flags().BoolVarP(&flags.export, "export", "e", false, "show env vars with export command")
+	cmd.Flags().BoolVar(&flags.reveal, "reveal", false, "show the values of secret env vars. encrypted values are decrypted")
 	return cmd
 }
+
+// hasSecret checks if any of envs is secret
+func hasSecret(envs config.Envs) bool {
+	for _, e := range envs {
+		if e.IsSecret() {
+			return true
+		}
+	}
+	return false
+}
+
+// revealedStatement returns statement of env with actual value. encrypted value is decrypted with cipher
+func revealedStatement(e *config.Env, cipher *secret.Cipher) (string, error) {
+	revealed := *e
+	if secret.IsEncrypted(e.Value) {
+		value, err := cipher.Decrypt(e.Value)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt %s: %w", e.Name, err)
+		}
+		revealed.Value = value
+	}
+	return revealed.RevealedStatement(), nil
+}
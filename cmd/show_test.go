@@ -133,4 +133,86 @@ var _ = Describe("Show", func() {
 			Expect(stderr.String()).NotTo(BeEmpty())
 		})
 	})
+
+	When("profile has value-from", func() {
+		BeforeEach(func() {
+			copy = false
+			args = []string{"secret", "--export"}
+			os.WriteFile(testConfigFile, []byte(`
+default: secret
+profiles:
+  secret:
+    env:
+      - name: TOKEN
+        value-from:
+          file: ~/.token
+      - name: PLAIN
+        value: plain
+`), 0644)
+		})
+		It("should print out the source of value as comment", func() {
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("# TOKEN from file:~/.token\n"))
+			Expect(stdout.String()).NotTo(ContainSubstring("export TOKEN"))
+			Expect(stdout.String()).To(ContainSubstring("export PLAIN=plain"))
+		})
+	})
+
+	When("profile has secret", func() {
+		var reveal string
+		BeforeEach(func() {
+			copy = false
+			reveal = ""
+			os.WriteFile(testConfigFile, []byte(`
+default: secret
+profiles:
+  secret:
+    env:
+      - name: TOKEN
+        value: my-token
+        secret: true
+      - name: PLAIN
+        value: plain
+`), 0644)
+		})
+		JustBeforeEach(func() {
+			// run again with the input of confirmation
+			if reveal != "" {
+				stdout.Reset()
+				cmd = showCommand()
+				cmd.SetOut(&stdout)
+				cmd.SetErr(&stderr)
+				cmd.SetIn(bytes.NewBufferString(reveal))
+				cmd.SetArgs([]string{"secret", "--reveal"})
+				err = cmd.Execute()
+			}
+		})
+		When("show without reveal", func() {
+			It("should mask the secret value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stdout.String()).To(ContainSubstring("TOKEN=" + config.MaskedValue))
+				Expect(stdout.String()).NotTo(ContainSubstring("my-token"))
+				Expect(stdout.String()).To(ContainSubstring("PLAIN=plain"))
+			})
+		})
+		When("reveal is confirmed", func() {
+			BeforeEach(func() {
+				reveal = "y\n"
+			})
+			It("should show the secret value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stdout.String()).To(ContainSubstring("TOKEN=my-token"))
+			})
+		})
+		When("reveal is not confirmed", func() {
+			BeforeEach(func() {
+				reveal = "n\n"
+			})
+			It("should not show the secret value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(stdout.String()).NotTo(ContainSubstring("my-token"))
+				Expect(stdout.String()).To(ContainSubstring("Cancelled"))
+			})
+		})
+	})
 })
@@ -13,6 +13,8 @@ func init() {
 // flags struct for start command
 type startFlags struct {
 	skipInitScript bool
+	env            []string
+	debugEnv       bool
 }
 
 // example of delete command
@@ -47,12 +49,22 @@ func startCommand(sh *shell.ShellCommand) *cobra.Command {
 			if err != nil {
 				return err
 			}
-			profile, err := currentProfile(cfg, args)
+			profile, err := currentResolvedProfile(cfg, args)
 			if err != nil {
 				checkErrorAndPrintCommandExample(cmd, err)
 				return err
 			}
 
+			// refuse to run the scripts and commands of profile that user didn't allow
+			if err := checkProfileAllowed(cfg, profile, flags.skipInitScript); err != nil {
+				return err
+			}
+
+			// set config and runtime overrides to shell command
+			if err := configureShellCommand(sh, cfg, flags.env, flags.debugEnv); err != nil {
+				return err
+			}
+
 			// ignore error message from shell. let shell print out the errors
 			sh.StartShell(profile, flags.skipInitScript)
 
@@ -61,6 +73,8 @@ func startCommand(sh *shell.ShellCommand) *cobra.Command {
 	}
 
 	cmd.Flags().BoolVarP(&flags.skipInitScript, "skip-init", "s", false, `Skip running initialization scripts from the profile's "init-script" during shell startup`)
+	cmd.Flags().StringArrayVarP(&flags.env, "env", "e", []string{}, "'VAR=VAL' format of string that overrides env of the profile")
+	cmd.Flags().BoolVar(&flags.debugEnv, "debug-env", false, "print env vars of the shell session with the layer that each value came from")
 
 	return cmd
 }
@@ -68,6 +68,7 @@ var _ = Describe("Start Command", func() {
 		if copy {
 			original, _ := os.ReadFile("../testdata/config.yaml")
 			os.WriteFile(testConfigFile, original, 0644)
+			allowAllProfiles()
 		}
 
 		cmd.SetArgs(args)                               // set the arg for each test case
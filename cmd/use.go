@@ -3,6 +3,7 @@ package cmd
 import (
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
 )
 
 func init() {
@@ -49,13 +50,15 @@ func useCommand() *cobra.Command {
 			}
 
 			// set selected profile as default
-			cfg.SetDefault(profile.Name)
-
-			if err := configFile.Save(); err != nil {
+			err = configFile.Update(func(cfg *config.Config) error {
+				cfg.SetDefault(profile.Name)
+				return nil
+			})
+			if err != nil {
 				return err
 			}
 
-			cmd.Println("Default profile is set to", color.GreenString(cfg.Default))
+			cmd.Println("Default profile is set to", color.GreenString(profile.Name))
 
 			return nil
 		},
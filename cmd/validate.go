@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func init() {
+	rootCmd.AddCommand(validateCommand())
+}
+
+// example of validate command
+func cmdExampleValidate() string {
+	return `
+  # validate config file and print the problems with line and column
+  envp validate
+  `
+}
+
+// validateCommand validates the config file. it returns error if any error is found so that it can be used for pre-commit
+func validateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:          "validate",
+		Short:        "Validate config file",
+		SilenceUsage: true,
+		Example:      cmdExampleValidate(),
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// config file of write layer is validated
+			target := configFile.Target()
+			b, err := os.ReadFile(target.Name())
+			if err != nil {
+				return err
+			}
+
+			count := 0
+			for _, d := range config.ValidateContent(target.Name(), b) {
+				switch d.Severity {
+				case config.SeverityError:
+					count++
+					cmd.Println(color.RedString(d.String()))
+				default:
+					cmd.Println(color.YellowString(d.String()))
+				}
+			}
+			if count > 0 {
+				return fmt.Errorf("%d errors found in config file", count)
+			}
+			cmd.Println("Config file is valid")
+			return nil
+		},
+	}
+	return cmd
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate Command", func() {
+	var (
+		stdout bytes.Buffer
+		config string
+		err    error
+	)
+
+	JustBeforeEach(func() {
+		stdout.Reset()
+		configFileName = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		os.WriteFile(configFileName, []byte(config), 0600)
+		initConfig()
+
+		cmd := validateCommand()
+		cmd.SetOut(&stdout)
+		cmd.SetErr(&stdout)
+		cmd.SetArgs([]string{})
+		err = cmd.Execute()
+	})
+
+	When("config file has errors", func() {
+		BeforeEach(func() {
+			config = "default: missing\nprofiles:\n  a:\n    env:\n    - name: MY-VAR\n      value: a\n"
+		})
+
+		It("should print the errors with position and return error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("config.yaml:1:10: error: default profile missing is not existing"))
+			Expect(stdout.String()).To(ContainSubstring("config.yaml:5:13: error: profiles.a.env[0].name \"MY-VAR\" must match"))
+		})
+	})
+
+	When("config file is valid", func() {
+		BeforeEach(func() {
+			config = "default: a\nprofiles:\n  a:\n    env:\n    - name: VAR\n      value: a\n"
+		})
+
+		It("should not return error", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(stdout.String()).To(ContainSubstring("Config file is valid"))
+		})
+	})
+})
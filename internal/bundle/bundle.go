@@ -0,0 +1,147 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sunggun-yu/envp/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// Version is version of the bundle format
+	Version = "v1"
+	// signingContext is prepended to the canonical serialization so that signature of bundle cannot be reused for other purpose
+	signingContext = "envp-bundle-v1\n"
+)
+
+// Bundle is set of profiles to be distributed to team. it can be signed with ed25519 key
+type Bundle struct {
+	Version   string           `yaml:"version"`
+	Profiles  *config.Profiles `yaml:"profiles"`
+	Signature *Signature       `yaml:"signature,omitempty"`
+}
+
+// Signature is ed25519 signature of the bundle
+type Signature struct {
+	KeyID string `yaml:"key-id"`
+	Value string `yaml:"value"` // base64 encoded signature
+}
+
+// BundleNotSignedError is an error when the bundle has no signature
+type BundleNotSignedError struct{}
+
+// NewBundleNotSignedError create new BundleNotSignedError
+func NewBundleNotSignedError() *BundleNotSignedError {
+	return &BundleNotSignedError{}
+}
+
+// Error is to make BundleNotSignedError errors
+func (e *BundleNotSignedError) Error() string {
+	return "bundle is not signed"
+}
+
+// SignatureNotVerifiedError is an error when the signature is not valid or not signed by trusted keys
+type SignatureNotVerifiedError struct {
+	keyID string
+}
+
+// NewSignatureNotVerifiedError create new SignatureNotVerifiedError
+func NewSignatureNotVerifiedError(keyID string) *SignatureNotVerifiedError {
+	return &SignatureNotVerifiedError{
+		keyID: keyID,
+	}
+}
+
+// Error is to make SignatureNotVerifiedError errors
+func (e *SignatureNotVerifiedError) Error() string {
+	return fmt.Sprintf("signature of key %s is not valid or the key is not trusted", e.keyID)
+}
+
+// ReadFile reads the bundle file
+func ReadFile(name string) (*Bundle, error) {
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := yaml.Unmarshal(b, &bundle); err != nil {
+		return nil, err
+	}
+	if bundle.Version != Version {
+		return nil, fmt.Errorf("bundle version %q is not supported", bundle.Version)
+	}
+	if bundle.Profiles == nil {
+		bundle.Profiles = &config.Profiles{}
+	}
+	return &bundle, nil
+}
+
+// WriteFile writes the bundle file
+func (b *Bundle) WriteFile(name string) error {
+	out, err := yaml.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(name, out, 0644)
+}
+
+// canonical returns canonical serialization of Profiles that is signed.
+// profiles are converted to generic values through yaml and encoded as json that has sorted keys without spaces,
+// so that it doesn't depend on formatting, comments and key order of the bundle file
+func (b *Bundle) canonical() ([]byte, error) {
+	y, err := yaml.Marshal(b.Profiles)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := yaml.Unmarshal(y, &generic); err != nil {
+		return nil, err
+	}
+	j, err := json.Marshal(generic)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(signingContext), j...), nil
+}
+
+// Sign signs Profiles of the bundle with private key. existing signature is replaced
+func (b *Bundle) Sign(priv ed25519.PrivateKey) error {
+	msg, err := b.canonical()
+	if err != nil {
+		return err
+	}
+	b.Signature = &Signature{
+		KeyID: KeyID(priv.Public().(ed25519.PublicKey)),
+		Value: base64.StdEncoding.EncodeToString(ed25519.Sign(priv, msg)),
+	}
+	return nil
+}
+
+// Verify verifies the signature with trusted keys and returns the key that signed the bundle
+func (b *Bundle) Verify(keys []config.TrustedKey) (*config.TrustedKey, error) {
+	if b.Signature == nil {
+		return nil, NewBundleNotSignedError()
+	}
+	sig, err := base64.StdEncoding.DecodeString(b.Signature.Value)
+	if err != nil {
+		return nil, NewSignatureNotVerifiedError(b.Signature.KeyID)
+	}
+	msg, err := b.canonical()
+	if err != nil {
+		return nil, err
+	}
+	for i, k := range keys {
+		pub, err := ParsePublicKey(k.PublicKey)
+		if err != nil {
+			return nil, fmt.Errorf("trusted key %s: %w", k.Name, err)
+		}
+		if KeyID(pub) == b.Signature.KeyID && ed25519.Verify(pub, msg, sig) {
+			return &keys[i], nil
+		}
+	}
+	return nil, NewSignatureNotVerifiedError(b.Signature.KeyID)
+}
@@ -0,0 +1,104 @@
+package bundle
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+const testBundle = `version: v1
+profiles:
+  team:
+    desc: team profiles
+    env:
+      - name: HTTP_PROXY
+        value: http://proxy:3128
+    dev:
+      env:
+        - name: STAGE
+          value: dev
+`
+
+// same profiles in other format and order with comments
+const testBundleReformatted = `# comment
+profiles:
+  team:
+    dev:
+      env: [{name: STAGE, value: dev}]
+    env:
+    - value: http://proxy:3128
+      name: HTTP_PROXY
+    desc: "team profiles"
+version: v1
+`
+
+func writeBundle(t *testing.T, content string) string {
+	name := filepath.Join(t.TempDir(), "bundle.yaml")
+	assert.NoError(t, os.WriteFile(name, []byte(content), 0644))
+	return name
+}
+
+func TestSignAndVerify(t *testing.T) {
+	pub, priv, err := GenerateKey()
+	assert.NoError(t, err)
+	privateKey, err := ParsePrivateKey(priv)
+	assert.NoError(t, err)
+	trusted := []config.TrustedKey{{Name: "team", PublicKey: pub}}
+
+	name := writeBundle(t, testBundle)
+	b, err := ReadFile(name)
+	assert.NoError(t, err)
+
+	var notSigned *BundleNotSignedError
+	_, err = b.Verify(trusted)
+	assert.ErrorAs(t, err, &notSigned)
+
+	assert.NoError(t, b.Sign(privateKey))
+	assert.NoError(t, b.WriteFile(name))
+
+	// verify after reading the signed file
+	signed, err := ReadFile(name)
+	assert.NoError(t, err)
+	key, err := signed.Verify(trusted)
+	assert.NoError(t, err)
+	assert.Equal(t, "team", key.Name)
+
+	// signature doesn't depend on the format of the file
+	reformatted, err := ReadFile(writeBundle(t, testBundleReformatted))
+	assert.NoError(t, err)
+	reformatted.Signature = signed.Signature
+	_, err = reformatted.Verify(trusted)
+	assert.NoError(t, err)
+
+	// untrusted key
+	otherPub, _, _ := GenerateKey()
+	var notVerified *SignatureNotVerifiedError
+	_, err = signed.Verify([]config.TrustedKey{{Name: "other", PublicKey: otherPub}})
+	assert.ErrorAs(t, err, &notVerified)
+
+	// tampered
+	(*signed.Profiles)["team"].Env[0].Value = "http://evil:3128"
+	_, err = signed.Verify(trusted)
+	assert.True(t, errors.As(err, &notVerified))
+}
+
+func TestReadFile(t *testing.T) {
+	_, err := ReadFile(writeBundle(t, "version: v0\nprofiles: {}\n"))
+	assert.Error(t, err)
+}
+
+func TestReadPrivateKeyFile(t *testing.T) {
+	_, priv, _ := GenerateKey()
+	name := filepath.Join(t.TempDir(), "key")
+	assert.NoError(t, os.WriteFile(name, []byte(priv+"\n"), 0644))
+	_, err := ReadPrivateKeyFile(name)
+	assert.Error(t, err)
+
+	assert.NoError(t, os.Chmod(name, 0600))
+	_, err = ReadPrivateKeyFile(name)
+	assert.NoError(t, err)
+}
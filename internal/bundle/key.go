@@ -0,0 +1,61 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// GenerateKey generates ed25519 key pair and returns them in base64 encoding
+func GenerateKey() (publicKey, privateKey string, err error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", "", err
+	}
+	return base64.StdEncoding.EncodeToString(pub), base64.StdEncoding.EncodeToString(priv.Seed()), nil
+}
+
+// ParsePublicKey parses base64 encoded ed25519 public key
+func ParsePublicKey(s string) (ed25519.PublicKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid ed25519 public key")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// ParsePrivateKey parses base64 encoded seed of ed25519 private key
+func ParsePrivateKey(s string) (ed25519.PrivateKey, error) {
+	b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+	if err != nil || len(b) != ed25519.SeedSize {
+		return nil, fmt.Errorf("invalid ed25519 private key")
+	}
+	return ed25519.NewKeyFromSeed(b), nil
+}
+
+// ReadPrivateKeyFile reads the private key file. the file must not be accessible by group and others
+func ReadPrivateKeyFile(name string) (ed25519.PrivateKey, error) {
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return nil, fmt.Errorf("private key file %s is accessible by group or others (%v). it should be 0600 or 0400", name, fi.Mode().Perm())
+	}
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePrivateKey(string(b))
+}
+
+// KeyID returns id of the public key. it is first 16 chars of hex of sha256 of the key
+func KeyID(pub ed25519.PublicKey) string {
+	h := sha256.Sum256(pub)
+	return hex.EncodeToString(h[:])[:16]
+}
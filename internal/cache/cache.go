@@ -0,0 +1,196 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sunggun-yu/envp/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// CacheEntry is cached value of env var
+type CacheEntry struct {
+	Key       string    `yaml:"key"`
+	Profile   string    `yaml:"profile"`
+	Name      string    `yaml:"name"`
+	Value     string    `yaml:"value"`
+	CreatedAt time.Time `yaml:"created-at"`
+	ExpiresAt time.Time `yaml:"expires-at"`
+}
+
+// Expired returns whether the entry is expired at t
+func (e *CacheEntry) Expired(t time.Time) bool {
+	return !t.Before(e.ExpiresAt)
+}
+
+// cacheData is content of the cache file
+type cacheData struct {
+	// ConfigHash is hash of config file that entries are created with. entries are invalidated when config is changed
+	ConfigHash string        `yaml:"config-hash"`
+	Entries    []*CacheEntry `yaml:"entries"`
+}
+
+// CacheFile is struct that representing the cache file of evaluated env values
+// the file is created with 0600 permission since it may contain credentials
+type CacheFile struct {
+	mu         sync.Mutex
+	name       string
+	configHash string
+	now        func() time.Time
+}
+
+// NewCacheFile returns CacheFile. configHash is hash of current config file to invalidate the entries of previous config.
+// file and its directory will be created when the first entry is saved.
+func NewCacheFile(name, configHash string) (*CacheFile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty cache file name")
+	}
+	// expand and replace file path if it is referring home dir, `~`, `$HOME`
+	p, err := util.ExpandHomeDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &CacheFile{
+		name:       p,
+		configHash: configHash,
+		now:        time.Now,
+	}, nil
+}
+
+// Key returns key of entry for expression of env var in the profile
+func Key(profile, name, expression string) string {
+	h := sha256.Sum256([]byte(fmt.Sprint(profile, "\x00", name, "\x00", expression)))
+	return hex.EncodeToString(h[:])
+}
+
+// Get returns cached value of expression of env var in the profile. false will be returned if it is not cached or expired
+func (c *CacheFile) Get(profile, name, expression string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.read()
+	if err != nil {
+		return "", false
+	}
+	key := Key(profile, name, expression)
+	for _, e := range data.Entries {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return "", false
+}
+
+// Set caches value of expression of env var in the profile for ttl
+func (c *CacheFile) Set(profile, name, expression, value string, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// other envp process may set the entries at the same time. lock across read and write not to lose them
+	if _, err := util.EnsureConfigFilePath(filepath.Dir(c.name)); err != nil {
+		return err
+	}
+	unlock, err := util.LockFile(c.name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	data, err := c.read()
+	if err != nil {
+		// broken cache file will be overwritten
+		data = &cacheData{ConfigHash: c.configHash}
+	}
+
+	now := c.now()
+	entry := &CacheEntry{
+		Key:       Key(profile, name, expression),
+		Profile:   profile,
+		Name:      name,
+		Value:     value,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	entries := []*CacheEntry{entry}
+	for _, e := range data.Entries {
+		if e.Key != entry.Key {
+			entries = append(entries, e)
+		}
+	}
+	data.Entries = entries
+	return c.write(data)
+}
+
+// List returns entries that are not expired and valid for current config in order of profile and name
+func (c *CacheFile) List() ([]*CacheEntry, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data, err := c.read()
+	if err != nil {
+		return nil, err
+	}
+	entries := data.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Profile != entries[j].Profile {
+			return entries[i].Profile < entries[j].Profile
+		}
+		return entries[i].Name < entries[j].Name
+	})
+	return entries, nil
+}
+
+// Clear deletes all the entries by removing the cache file
+func (c *CacheFile) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if err := os.Remove(c.name); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// read reads the cache file. entries that are expired or created with other config are dropped
+func (c *CacheFile) read() (*cacheData, error) {
+	data := &cacheData{ConfigHash: c.configHash}
+
+	b, err := os.ReadFile(c.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		return nil, err
+	}
+
+	var stored cacheData
+	if err := yaml.Unmarshal(b, &stored); err != nil {
+		return nil, err
+	}
+	// config is changed
+	if stored.ConfigHash != c.configHash {
+		return data, nil
+	}
+	now := c.now()
+	for _, e := range stored.Entries {
+		if !e.Expired(now) {
+			data.Entries = append(data.Entries, e)
+		}
+	}
+	return data, nil
+}
+
+// write writes the cache file atomically with 0600 permission. caller must hold the lock of the cache file
+func (c *CacheFile) write(data *cacheData) error {
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(c.name, b, 0600)
+}
@@ -0,0 +1,124 @@
+package cache
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CacheFile", func() {
+
+	var (
+		testFile string
+		cf       *CacheFile
+		now      time.Time
+	)
+
+	BeforeEach(func() {
+		testFile = filepath.Join(os.TempDir(), fmt.Sprintf("envp-cache-%v", GinkgoRandomSeed()), "state", "cache.yaml")
+		DeferCleanup(func() {
+			os.RemoveAll(filepath.Dir(filepath.Dir(testFile)))
+		})
+		now = time.Now()
+		var err error
+		cf, err = NewCacheFile(testFile, "hash-1")
+		Expect(err).NotTo(HaveOccurred())
+		cf.now = func() time.Time { return now }
+	})
+
+	When("value is cached", func() {
+		BeforeEach(func() {
+			Expect(cf.Set("my-profile", "TOKEN", "$(print-token)", "token-value", 15*time.Minute)).To(Succeed())
+		})
+
+		It("should return cached value", func() {
+			v, ok := cf.Get("my-profile", "TOKEN", "$(print-token)")
+			Expect(ok).To(BeTrue())
+			Expect(v).To(Equal("token-value"))
+		})
+
+		It("should create the cache file with 0600 permission", func() {
+			fi, err := os.Stat(testFile)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+
+		It("should not return value of other profile, variable or expression", func() {
+			_, ok := cf.Get("other-profile", "TOKEN", "$(print-token)")
+			Expect(ok).To(BeFalse())
+			_, ok = cf.Get("my-profile", "OTHER", "$(print-token)")
+			Expect(ok).To(BeFalse())
+			_, ok = cf.Get("my-profile", "TOKEN", "$(print-other-token)")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should not return expired value", func() {
+			now = now.Add(15 * time.Minute)
+			_, ok := cf.Get("my-profile", "TOKEN", "$(print-token)")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should not return value when config is changed", func() {
+			other, _ := NewCacheFile(testFile, "hash-2")
+			_, ok := other.Get("my-profile", "TOKEN", "$(print-token)")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should list entries", func() {
+			Expect(cf.Set("a-profile", "TOKEN", "$(print-token)", "token-value", time.Minute)).To(Succeed())
+			entries, err := cf.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(2))
+			Expect(entries[0].Profile).To(Equal("a-profile"))
+			Expect(entries[1].Profile).To(Equal("my-profile"))
+		})
+
+		It("should overwrite the entry of same key", func() {
+			Expect(cf.Set("my-profile", "TOKEN", "$(print-token)", "new-token-value", time.Minute)).To(Succeed())
+			v, _ := cf.Get("my-profile", "TOKEN", "$(print-token)")
+			Expect(v).To(Equal("new-token-value"))
+			entries, _ := cf.List()
+			Expect(entries).To(HaveLen(1))
+		})
+
+		It("should clear entries", func() {
+			Expect(cf.Clear()).To(Succeed())
+			entries, err := cf.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(BeEmpty())
+			// clear again should not be error
+			Expect(cf.Clear()).To(Succeed())
+		})
+	})
+
+	When("values are cached by other instances at the same time", func() {
+		It("should keep all the entries", func() {
+			var wg sync.WaitGroup
+			for i := 0; i < 10; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer GinkgoRecover()
+					defer wg.Done()
+					other, _ := NewCacheFile(testFile, "hash-1")
+					Expect(other.Set("my-profile", fmt.Sprintf("VAR_%d", i), "$(cmd)", "value", time.Minute)).To(Succeed())
+				}(i)
+			}
+			wg.Wait()
+			entries, err := cf.List()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(entries).To(HaveLen(10))
+		})
+	})
+
+	When("cache file name is empty", func() {
+		It("should return error", func() {
+			_, err := NewCacheFile("", "hash")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
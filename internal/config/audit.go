@@ -0,0 +1,30 @@
+package config
+
+import "path"
+
+// AuditConfig is configuration of audit command
+type AuditConfig struct {
+	// Allowlist is list of findings that are not reported
+	Allowlist []AuditAllow `mapstructure:"allowlist" yaml:"allowlist,omitempty"`
+}
+
+// AuditAllow allows the finding of audit. each field is glob pattern and empty field matches anything
+type AuditAllow struct {
+	Profile string `mapstructure:"profile" yaml:"profile,omitempty"`
+	Name    string `mapstructure:"name" yaml:"name,omitempty"`
+	Rule    string `mapstructure:"rule" yaml:"rule,omitempty"`
+}
+
+// Allows checks if the finding of rule in env var name of profile is allowed
+func (a AuditAllow) Allows(profile, name, rule string) bool {
+	return globMatch(a.Profile, profile) && globMatch(a.Name, name) && globMatch(a.Rule, rule)
+}
+
+// globMatch checks if s matches pattern. empty pattern matches anything
+func globMatch(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := path.Match(pattern, s)
+	return err == nil && ok
+}
@@ -0,0 +1,279 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sunggun-yu/envp/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultMaxBackups is the number of backups of the config file to keep by default
+const DefaultMaxBackups = 10
+
+// backupIDLayout is the time layout of backup id. id is sortable in order of time
+const backupIDLayout = "20060102T150405.000000000Z"
+
+// Backup is the content of the config file before it was changed
+type Backup struct {
+	ID   string
+	Time time.Time
+	name string
+}
+
+// BackupNotExistingError is an error when the backup is not existing
+type BackupNotExistingError struct {
+	id string
+}
+
+// NewBackupNotExistingError create new BackupNotExistingError
+func NewBackupNotExistingError(id string) *BackupNotExistingError {
+	return &BackupNotExistingError{
+		id: id,
+	}
+}
+
+// Error is to make BackupNotExistingError errors
+func (e *BackupNotExistingError) Error() string {
+	return fmt.Sprintf("backup %s is not existing", e.id)
+}
+
+// SetMaxBackups sets the number of backups to keep. backup is disabled if n is 0 or less
+func (c *ConfigFile) SetMaxBackups(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxBackups = n
+	for _, lf := range c.layers {
+		lf.SetMaxBackups(n)
+	}
+}
+
+// Backups returns the backups of the config file. latest one comes first
+func (c *ConfigFile) Backups() ([]Backup, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.backups()
+}
+
+// ReadBackup returns the content of the backup
+func (c *ConfigFile) ReadBackup(id string) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.readBackup(id)
+}
+
+// Restore replaces the config file with the backup. current config file is backed up as well so that restore can be reverted
+func (c *ConfigFile) Restore(id string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	unlock, err := util.LockFile(c.name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	b, err := c.readBackup(id)
+	if err != nil {
+		return err
+	}
+	// do not restore the broken backup
+	cfg, err := ParseConfig(b)
+	if err != nil {
+		return err
+	}
+	if err := c.validateConfig(cfg); err != nil {
+		return err
+	}
+	return c.writeFile(b, false)
+}
+
+// backupDir returns directory of the backups. it is in the directory of config file
+func (c *ConfigFile) backupDir() string {
+	return filepath.Join(filepath.Dir(c.name), "backups")
+}
+
+// backup copies the current config file into the backup directory if it is going to be changed to b
+// and removes the old backups over the max. it is backed up even if backup is disabled if force is true
+func (c *ConfigFile) backup(b []byte, force bool) error {
+	keep := c.maxBackups
+	if force {
+		keep = max(keep, 1)
+	}
+	if keep <= 0 {
+		return nil
+	}
+	current, err := os.ReadFile(c.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	// nothing to back up
+	if len(current) == 0 || bytes.Equal(current, b) {
+		return nil
+	}
+
+	if err := os.MkdirAll(c.backupDir(), 0700); err != nil {
+		return err
+	}
+	id := time.Now().UTC().Format(backupIDLayout)
+	if err := util.WriteFileAtomic(filepath.Join(c.backupDir(), c.backupPrefix()+id), current, 0600); err != nil {
+		return err
+	}
+
+	backups, err := c.backups()
+	if err != nil {
+		return err
+	}
+	for i := keep; i < len(backups); i++ {
+		if err := os.Remove(backups[i].name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// backupPrefix returns file name prefix of the backups. config files in same directory have their own backups
+func (c *ConfigFile) backupPrefix() string {
+	return filepath.Base(c.name) + "."
+}
+
+// backups returns the backups in the backup directory. latest one comes first
+func (c *ConfigFile) backups() ([]Backup, error) {
+	entries, err := os.ReadDir(c.backupDir())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	backups := []Backup{}
+	for _, e := range entries {
+		id, ok := strings.CutPrefix(e.Name(), c.backupPrefix())
+		if !ok || e.IsDir() {
+			continue
+		}
+		t, err := time.Parse(backupIDLayout, id)
+		if err != nil {
+			// not a backup. e.g. temp file of atomic write
+			continue
+		}
+		backups = append(backups, Backup{
+			ID:   id,
+			Time: t,
+			name: filepath.Join(c.backupDir(), e.Name()),
+		})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].ID > backups[j].ID
+	})
+	return backups, nil
+}
+
+// readBackup returns the content of the backup
+func (c *ConfigFile) readBackup(id string) ([]byte, error) {
+	backups, err := c.backups()
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backups {
+		if b.ID == id {
+			return os.ReadFile(b.name)
+		}
+	}
+	return nil, NewBackupNotExistingError(id)
+}
+
+// Summary returns one line summary of the changes from old to new config
+func Summary(old, new *Config) string {
+	changes := []string{}
+
+	if old.version() != new.version() {
+		changes = append(changes, fmt.Sprintf("migrated to version %d", new.version()))
+	}
+	if old.Default != new.Default {
+		if new.Default == "" {
+			changes = append(changes, "unset default profile")
+		} else {
+			changes = append(changes, fmt.Sprintf("set default profile %s", new.Default))
+		}
+	}
+
+	oldProfiles, newProfiles := profileContents(old), profileContents(new)
+	added, deleted, changed := []string{}, []string{}, []string{}
+	for _, name := range sortedKeys(newProfiles) {
+		content, ok := oldProfiles[name]
+		switch {
+		case !ok:
+			added = append(added, name)
+		case content != newProfiles[name]:
+			changed = append(changed, name)
+		}
+	}
+	for _, name := range sortedKeys(oldProfiles) {
+		if _, ok := newProfiles[name]; !ok {
+			deleted = append(deleted, name)
+		}
+	}
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("added %s", strings.Join(added, ", ")))
+	}
+	if len(changed) > 0 {
+		changes = append(changes, fmt.Sprintf("changed %s", strings.Join(changed, ", ")))
+	}
+	if len(deleted) > 0 {
+		changes = append(changes, fmt.Sprintf("deleted %s", strings.Join(deleted, ", ")))
+	}
+
+	if settingsContent(old) != settingsContent(new) {
+		changes = append(changes, "changed settings")
+	}
+
+	if len(changes) == 0 {
+		return "no changes"
+	}
+	return strings.Join(changes, "; ")
+}
+
+// profileContents returns yaml of the profiles without their child profiles by name
+func profileContents(c *Config) map[string]string {
+	contents := map[string]string{}
+	if c.Profiles == nil {
+		return contents
+	}
+	c.Profiles.Walk(func(name string, p *Profile) {
+		cp := *p
+		cp.Profiles = nil
+		b, _ := yaml.Marshal(cp)
+		contents[name] = string(b)
+	})
+	return contents
+}
+
+// settingsContent returns yaml of the config except for version, default and profiles
+func settingsContent(c *Config) string {
+	cp := *c
+	cp.Version = 0
+	cp.Default = ""
+	cp.Profiles = nil
+	b, _ := yaml.Marshal(&cp)
+	return string(b)
+}
+
+// sortedKeys returns sorted keys of the map
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
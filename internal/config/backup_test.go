@@ -0,0 +1,132 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Backup", func() {
+	var (
+		testFile string
+		cf       *ConfigFile
+	)
+
+	addProfile := func(name string) {
+		Expect(cf.Update(func(c *Config) error {
+			return c.SetProfile(name, Profile{Env: Envs{{Name: "VAR", Value: name}}})
+		})).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		var err error
+		testFile = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		cf, err = NewConfigFile(testFile)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("config file is updated", func() {
+		It("should back up the content before the update", func() {
+			before, _ := os.ReadFile(testFile)
+			addProfile("a")
+
+			backups, err := cf.Backups()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(backups).To(HaveLen(1))
+			b, err := cf.ReadBackup(backups[0].ID)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(b).To(Equal(before))
+
+			fi, _ := os.Stat(filepath.Join(filepath.Dir(testFile), "backups", "config.yaml."+backups[0].ID))
+			Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		})
+	})
+
+	When("backups are over the max", func() {
+		It("should keep only the latest backups", func() {
+			cf.SetMaxBackups(2)
+			addProfile("a")
+			addProfile("b")
+			addProfile("c")
+
+			backups, _ := cf.Backups()
+			Expect(backups).To(HaveLen(2))
+			Expect(backups[0].ID > backups[1].ID).To(BeTrue())
+			b, _ := cf.ReadBackup(backups[0].ID)
+			Expect(string(b)).To(ContainSubstring("b:"))
+			Expect(string(b)).NotTo(ContainSubstring("c:"))
+		})
+	})
+
+	When("backup is disabled", func() {
+		It("should not back up", func() {
+			cf.SetMaxBackups(0)
+			addProfile("a")
+			backups, _ := cf.Backups()
+			Expect(backups).To(BeEmpty())
+		})
+	})
+
+	When("restore the backup", func() {
+		It("should replace the config file with the backup and back up the current one", func() {
+			addProfile("a")
+			addProfile("b")
+			backups, _ := cf.Backups()
+
+			Expect(cf.Restore(backups[0].ID)).To(Succeed())
+			c, _ := cf.Read()
+			Expect(c.ProfileNames()).To(Equal([]string{"a"}))
+
+			backups, _ = cf.Backups()
+			Expect(backups).To(HaveLen(3))
+		})
+
+		It("should return error if backup is not existing", func() {
+			var notExisting *BackupNotExistingError
+			Expect(errors.As(cf.Restore("unknown"), &notExisting)).To(BeTrue())
+		})
+	})
+})
+
+var _ = Describe("Summary", func() {
+	parse := func(s string) *Config {
+		c, err := ParseConfig([]byte(s))
+		Expect(err).NotTo(HaveOccurred())
+		return c
+	}
+
+	It("should summarize the changes", func() {
+		old := parse(`default: a
+profiles:
+  a:
+    env:
+    - name: VAR
+      value: a
+  b:
+    env:
+    - name: VAR
+      value: b
+`)
+		new := parse(`default: c
+expansion: legacy
+profiles:
+  a:
+    env:
+    - name: VAR
+      value: changed
+  c:
+    env:
+    - name: VAR
+      value: c
+`)
+		Expect(Summary(old, new)).To(Equal("set default profile c; added c; changed a; deleted b; changed settings"))
+	})
+
+	It("should say no changes", func() {
+		c := parse("default: a\nprofiles: {}\n")
+		Expect(Summary(c, c)).To(Equal("no changes"))
+	})
+})
@@ -0,0 +1,15 @@
+package config
+
+// BundleConfig is configuration of importing profile bundles
+type BundleConfig struct {
+	// RequireSignature refuses to import the bundle that is not signed by one of TrustedKeys
+	RequireSignature bool `mapstructure:"require-signature" yaml:"require-signature,omitempty"`
+	// TrustedKeys is public keys of the bundle signers that are trusted
+	TrustedKeys []TrustedKey `mapstructure:"trusted-keys" yaml:"trusted-keys,omitempty"`
+}
+
+// TrustedKey is ed25519 public key of the bundle signer
+type TrustedKey struct {
+	Name      string `mapstructure:"name" yaml:"name"`
+	PublicKey string `mapstructure:"public-key" yaml:"public-key"` // base64 encoded ed25519 public key
+}
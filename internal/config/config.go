@@ -1,16 +1,35 @@
 package config
 
 import (
+	"fmt"
 	"sync"
+	"time"
 )
 
 // Config is struct that represents configuration of config file
 type Config struct {
-	mu       *sync.RWMutex
-	Default  string    `mapstructure:"default" yaml:"default"`
-	Profiles *Profiles `mapstructure:"profiles" yaml:"profiles"`
+	mu           *sync.RWMutex
+	hash         string            // hash of the config file content that Config is read from
+	raw          []byte            // config file content that Config is read from. changes are applied to it to preserve comments and layout
+	migrations   []Migration       // migrations that are applied since Config is read
+	sources      map[string]string // config file of the profiles by name if Config is merged from the layers
+	projects     map[string]bool   // profiles that have their own fields from the config file of project layer
+	Version      int               `mapstructure:"version" yaml:"version,omitempty"`
+	Default      string            `mapstructure:"default" yaml:"default"`
+	Expansion    string            `mapstructure:"expansion" yaml:"expansion,omitempty"`
+	ValueTimeout time.Duration     `mapstructure:"value-timeout" yaml:"value-timeout,omitempty"`
+	TotalTimeout time.Duration     `mapstructure:"total-timeout" yaml:"total-timeout,omitempty"`
+	Audit        *AuditConfig      `mapstructure:"audit" yaml:"audit,omitempty"`
+	Bundle       *BundleConfig     `mapstructure:"bundle" yaml:"bundle,omitempty"`
+	Profiles     *Profiles         `mapstructure:"profiles" yaml:"profiles"`
 }
 
+// expansion modes of env values
+const (
+	ExpansionNative = "native" // expand values natively. only explicit command substitution $(...) runs shell
+	ExpansionLegacy = "legacy" // expand values with shell echo
+)
+
 // DefaultProfileNotSetError is error when default profile is not set
 type DefaultProfileNotSetError struct{}
 
@@ -24,17 +43,39 @@ func (e *DefaultProfileNotSetError) Error() string {
 	return "default profile is not set"
 }
 
-// SetMutex set the pointer of RWMutex
-func (c *Config) SetMutex(m *sync.RWMutex) {
-	c.mu = m
+// LegacyExpansion returns whether env values should be expanded with legacy shell echo
+func (c *Config) LegacyExpansion() bool {
+	return c.Expansion == ExpansionLegacy
 }
 
 // initMutex init RWMutext for Config
-// mutex of Config instance is pointer to make it possible to set mutext from ConfigFile for sync operation with file handliing
 func (c *Config) initMutex() {
 	if c.mu == nil {
-		c.SetMutex(new(sync.RWMutex))
+		c.mu = new(sync.RWMutex)
+	}
+}
+
+// Validate checks if the config is valid. env vars and extends of all the profiles are checked
+func (c *Config) Validate() error {
+	if c.Profiles == nil {
+		return nil
 	}
+	var err error
+	c.Profiles.Walk(func(name string, p *Profile) {
+		if err != nil {
+			return
+		}
+		for _, e := range p.Env {
+			if err = e.Validate(); err != nil {
+				err = fmt.Errorf("profile %s: %w", name, err)
+				return
+			}
+		}
+		if len(p.Extends) > 0 {
+			_, err = c.Profiles.ResolveProfile(name)
+		}
+	})
+	return err
 }
 
 // DefaultProfile returns default profile of config. it returns DefaultProfileNotSetError when default file is not set
@@ -72,6 +113,32 @@ func (c *Config) Profile(name string) (*NamedProfile, error) {
 	}, nil
 }
 
+// ResolvedProfile find and return NamedProfile of name that env and init-script of parent groups are merged into.
+// it should be used for running the profile. use Profile to update the profile in the Config
+func (c *Config) ResolvedProfile(name string) (*NamedProfile, error) {
+	c.initMutex()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	p, err := c.Profiles.ResolveProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return &NamedProfile{
+		Profile:   p,
+		Name:      name,
+		IsDefault: c.Default == name,
+	}, nil
+}
+
+// ResolvedDefaultProfile returns default profile of config that env and init-script of parent groups are merged into.
+// it returns DefaultProfileNotSetError when default file is not set
+func (c *Config) ResolvedDefaultProfile() (*NamedProfile, error) {
+	if c.Default == "" {
+		return nil, NewDefaultProfileNotSetError()
+	}
+	return c.ResolvedProfile(c.Default)
+}
+
 // SetDefault set the default profile
 func (c *Config) SetDefault(key string) {
 	c.initMutex()
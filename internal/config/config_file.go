@@ -1,6 +1,8 @@
 package config
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +15,7 @@ import (
 var (
 	// default config to be used when initiate the empty config file
 	defaultConfig = Config{
+		Version:  CurrentVersion,
 		Default:  "",
 		Profiles: &Profiles{},
 	}
@@ -20,10 +23,32 @@ var (
 
 // ConfigFile is struct that representing the envp config file
 // It contains Config and perform read and save operation
+// Config that is returned by Read is snapshot of the file. use Update to change the config file.
 type ConfigFile struct {
-	mu     sync.RWMutex
-	name   string
-	config *Config
+	mu         sync.RWMutex
+	name       string
+	maxBackups int                     // number of backups to keep. backup is disabled if it is 0
+	layer      string                  // layer of the file if it is one of the layers
+	layers     []*ConfigFile           // config files of the layers that Read merges. empty if the layers are not set
+	target     *ConfigFile             // config file of the layer that changes are written to
+	validate   func(cfg *Config) error // validates Config before it is written. Config of the layer is validated with the other layers
+}
+
+// ConfigFileChangedError is an error when the config file is changed by other process since it was read
+type ConfigFileChangedError struct {
+	name string
+}
+
+// NewConfigFileChangedError create new ConfigFileChangedError
+func NewConfigFileChangedError(name string) *ConfigFileChangedError {
+	return &ConfigFileChangedError{
+		name: name,
+	}
+}
+
+// Error is to make ConfigFileChangedError errors
+func (e *ConfigFileChangedError) Error() string {
+	return fmt.Sprintf("config file %s is changed by other process since it was read. run it again or use --force to overwrite", e.name)
 }
 
 // NewConfigFile returns ConfigFile. it create the config file directory and file if not exists
@@ -40,7 +65,8 @@ func NewConfigFile(name string) (*ConfigFile, error) {
 	filePath := filepath.Join(p, filepath.Base(name))
 
 	cf := &ConfigFile{
-		name: filePath,
+		name:       filePath,
+		maxBackups: DefaultMaxBackups,
 	}
 	// init config file if not exist
 	if err := cf.initConfigFile(); err != nil {
@@ -73,49 +99,212 @@ func (c *ConfigFile) initConfigFile() error {
 	defer f.Close()
 
 	// create default empty config file if file is empty (check by size)
-	fs, _ := f.Stat()
+	fs, err := f.Stat()
+	if err != nil {
+		return err
+	}
 	if fs.Size() == 0 {
-		b, _ := yaml.Marshal(&defaultConfig) // error can be ignored since it marshal from defined object
-		f.Write(b)                           // error can be ignored since it marshal from defined object
+		b, err := yaml.Marshal(&defaultConfig)
+		if err != nil {
+			return err
+		}
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+		return f.Sync()
 	}
 	return nil
 }
 
-// Read read config file return Config
+// Read reads config file and returns Config. Config is fresh snapshot of the file that is not shared with other callers
+// Config is merged from the layers if they are set. see SetLayers
 func (c *ConfigFile) Read() (*Config, error) {
+	if len(c.layers) > 0 {
+		return c.readMerged(nil, nil)
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.read()
+}
+
+// read reads config file into new Config
+func (c *ConfigFile) read() (*Config, error) {
+	b, err := os.ReadFile(c.name)
+	if err != nil {
+		return nil, err
+	}
+	cfg, err := ParseConfig(b)
+	if err != nil {
+		return nil, err
+	}
+	// upgrade the config of older version. it is written when config is saved
+	if err := cfg.Migrate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// ParseConfig parses the content of config file into Config
+func ParseConfig(b []byte) (*Config, error) {
+	cfg := &Config{}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return nil, err
+	}
+	if cfg.Profiles == nil {
+		cfg.Profiles = &Profiles{}
+	}
+	cfg.hash = hashOf(b)
+	cfg.raw = b
+	return cfg, nil
+}
+
+// Name returns path of the config file
+func (c *ConfigFile) Name() string {
+	return c.name
+}
+
+// Hash returns sha256 hash of the config file content. it can be used to detect the change of config file.
+// it is hash of all the layers if they are set
+func (c *ConfigFile) Hash() (string, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// unmarshal
-	b, _ := os.ReadFile(c.name)
-	if err := yaml.Unmarshal(b, &c.config); err != nil {
-		return nil, err
+	if len(c.layers) > 0 {
+		return c.hashLayers()
 	}
-	// set mutex to Config to synchronize object along with file operation
-	c.config.SetMutex(&c.mu)
-	return c.config, nil
+
+	b, err := os.ReadFile(c.name)
+	if err != nil {
+		return "", err
+	}
+	return hashOf(b), nil
 }
 
-// Save saves config as a file
-func (c *ConfigFile) Save() error {
+// hashOf returns sha256 hex of b
+func hashOf(b []byte) string {
+	h := sha256.Sum256(b)
+	return hex.EncodeToString(h[:])
+}
 
+// Update reloads the config file, applies fn to it, validates and saves it atomically.
+// the file is locked during the update so that concurrent updates of other processes are not lost.
+// nothing is saved if fn or validation returns error. fn is applied to the config file of write layer if the layers are set
+func (c *ConfigFile) Update(fn func(cfg *Config) error) error {
+	if t := c.Target(); t != c {
+		return t.Update(fn)
+	}
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
-	// Marshal config
-	if c.config == nil {
+	unlock, err := util.LockFile(c.name)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	cfg, err := c.read()
+	if err != nil {
+		return err
+	}
+	if err := fn(cfg); err != nil {
+		return err
+	}
+	return c.write(cfg)
+}
+
+// Save saves Config that is read by Read as a file.
+// it returns ConfigFileChangedError if the file is changed by other process since it was read, to not clobber the change
+func (c *ConfigFile) Save(cfg *Config) error {
+	return c.save(cfg, false)
+}
+
+// ForceSave saves Config as a file even if the file is changed by other process since it was read
+func (c *ConfigFile) ForceSave(cfg *Config) error {
+	return c.save(cfg, true)
+}
+
+// save saves Config with holding the lock of config file
+func (c *ConfigFile) save(cfg *Config, force bool) error {
+	if cfg == nil {
 		return fmt.Errorf("config is nil. nothing to write")
 	}
-	b, _ := yaml.Marshal(c.config) // error can be ignored since it marshal from defined object
+	if cfg.sources != nil {
+		return NewConfigMergedError()
+	}
+	if t := c.Target(); t != c {
+		return t.save(cfg, force)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
 
-	// open file with read/write and trunc flag
-	f, err := os.OpenFile(c.name, os.O_RDWR|os.O_TRUNC, 0600)
+	// lock the file to prevent other envp processes from writing it at the same time
+	unlock, err := util.LockFile(c.name)
 	if err != nil {
 		return err
 	}
-	defer f.Close()
+	defer unlock()
 
-	f.Write(b) // error can be ignored since it write file with guaranteed object and file permission
+	if !force {
+		current, err := os.ReadFile(c.name)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err == nil && hashOf(current) != cfg.hash {
+			return NewConfigFileChangedError(c.name)
+		}
+	}
+	return c.write(cfg)
+}
 
+// write validates Config and writes it into temp file and renames it to the config file
+func (c *ConfigFile) write(cfg *Config) error {
+	if err := c.validateConfig(cfg); err != nil {
+		return err
+	}
+	b, err := Marshal(cfg)
+	if err != nil {
+		return err
+	}
+	// original content of migrated config is always backed up
+	if err := c.writeFile(b, len(cfg.migrations) > 0); err != nil {
+		return err
+	}
+	cfg.hash = hashOf(b)
+	cfg.raw = b
+	cfg.migrations = nil
 	return nil
 }
+
+// validateConfig validates Config to be written
+func (c *ConfigFile) validateConfig(cfg *Config) error {
+	if c.validate != nil {
+		return c.validate(cfg)
+	}
+	return cfg.Validate()
+}
+
+// Marshal returns the content of config file for Config.
+// changes are applied to the content that Config is read from to keep comments and layout, and ConfigNotPatchableError is returned if it cannot be.
+// whole Config is marshaled if it is not read from the content
+func Marshal(cfg *Config) ([]byte, error) {
+	if len(cfg.raw) > 0 {
+		return patch(cfg.raw, cfg)
+	}
+	return yaml.Marshal(cfg)
+}
+
+// writeFile backs up the current config file and replaces it with b atomically. it is backed up even if backup is disabled if forceBackup is true
+func (c *ConfigFile) writeFile(b []byte, forceBackup bool) error {
+	// rename replaces the file even if it is read-only. respect the permission of existing file
+	if f, err := os.OpenFile(c.name, os.O_WRONLY, 0); err == nil {
+		f.Close()
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	if err := c.backup(b, forceBackup); err != nil {
+		return err
+	}
+	return util.WriteFileAtomic(c.name, b, 0600)
+}
@@ -1,6 +1,7 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -19,8 +20,8 @@ func TestConfigFile(t *testing.T) {
 	// assert
 	assert := assert.New(t)
 
-	testFile := "./testdata/multi-thread-test.yaml"
-	defer os.Remove(testFile) // remove file after testing
+	// lock file and backups are written next to the config file
+	testFile := filepath.Join(t.TempDir(), "multi-thread-test.yaml")
 
 	// when create ConfigFile by NewConfigFile
 	cf, err := NewConfigFile(testFile)
@@ -34,20 +35,16 @@ func TestConfigFile(t *testing.T) {
 		wg.Add(1)
 		// add profile as much as number of cases concurrently
 		go func(n int) {
-			c, err := cf.Read()
-
-			assert.NoError(err, "error should not occurred on read operation")
-
-			c.SetDefault(strconv.Itoa(n)) // it may not guarantee the order
-			c.SetProfile(fmt.Sprintf("hello.world-%v", n), Profile{
-				Desc: strconv.Itoa(n),
-				Env: Envs{
-					{Name: "VAR", Value: strconv.Itoa(n)},
-				},
+			err := cf.Update(func(c *Config) error {
+				c.SetDefault(strconv.Itoa(n)) // it may not guarantee the order
+				return c.SetProfile(fmt.Sprintf("hello.world-%v", n), Profile{
+					Desc: strconv.Itoa(n),
+					Env: Envs{
+						{Name: "VAR", Value: strconv.Itoa(n)},
+					},
+				})
 			})
-			// when perform right after update config
-			err = cf.Save()
-			assert.NoError(err, "error should not occurred on save")
+			assert.NoError(err, "error should not occurred on update")
 			wg.Done()
 		}(i)
 	}
@@ -66,7 +63,7 @@ func TestConfigFile(t *testing.T) {
 	assert.NoError(err, "error should not occurred on delete")
 
 	// when perform after update config
-	err = cf.Save()
+	err = cf.Save(c)
 	assert.NoError(err, "error should not occurred on save")
 
 	// when read after save the config file
@@ -98,8 +95,7 @@ func TestInitConfig(t *testing.T) {
 func TestRead(t *testing.T) {
 	// assert
 	assert := assert.New(t)
-	testFile := fmt.Sprintf("%v", GinkgoRandomSeed())
-	defer os.Remove(testFile) // remove file after testing
+	testFile := filepath.Join(t.TempDir(), "config.yaml")
 
 	t.Run("when create empty ConfigFile instance directly", func(t *testing.T) {
 		cf, _ := NewConfigFile(testFile)
@@ -112,7 +108,6 @@ func TestRead(t *testing.T) {
 		`
 		os.WriteFile(testFile, []byte(wrongData), 0600)
 
-		cf.config = nil
 		_, err := cf.Read()
 		assert.Error(err, "should occur error when have wrong format of config file")
 	})
@@ -123,21 +118,20 @@ func TestWrite(t *testing.T) {
 	assert := assert.New(t)
 
 	t.Run("when write without read - nil config", func(t *testing.T) {
-		testFile := fmt.Sprintf("%v", GinkgoRandomSeed())
-		defer os.Remove(testFile) // remove file after testing
+		testFile := filepath.Join(t.TempDir(), "config.yaml")
 		cf, _ := NewConfigFile(testFile)
-		err := cf.Save()
+		err := cf.Save(nil)
 		assert.Error(err, "should occur error when have wrong format of config file")
 	})
 
 	t.Run("when have no permission on config file", func(t *testing.T) {
-		testFile := fmt.Sprintf("%v", GinkgoRandomSeed())
-		defer os.Remove(testFile) // remove file after testing
+		// config file is migrated and backed up when it is saved. backups must not be left in the source tree
+		testFile := filepath.Join(t.TempDir(), "config.yaml")
 		cf, _ := NewConfigFile(testFile)
-		cf.Read()
+		c, _ := cf.Read()
 		// make it read-only
 		os.Chmod(testFile, 0400)
-		err := cf.Save()
+		err := cf.Save(c)
 		assert.Error(err, "should occur error when have wrong format of config file")
 	})
 }
@@ -200,3 +194,115 @@ var _ = Describe("NewConfigFile", func() {
 		})
 	})
 })
+
+var _ = Describe("Save", func() {
+	var (
+		testFile string
+		cf       *ConfigFile
+		cfg      *Config
+		err      error
+	)
+
+	BeforeEach(func() {
+		testFile = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		cf, err = NewConfigFile(testFile)
+		Expect(err).NotTo(HaveOccurred())
+		cfg, err = cf.Read()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("config file is changed by other process since it was read", func() {
+		BeforeEach(func() {
+			os.WriteFile(testFile, []byte("default: changed\nprofiles: {}\n"), 0600)
+		})
+
+		It("should not overwrite the change", func() {
+			var changedErr *ConfigFileChangedError
+			Expect(errors.As(cf.Save(cfg), &changedErr)).To(BeTrue())
+			b, _ := os.ReadFile(testFile)
+			Expect(string(b)).To(ContainSubstring("default: changed"))
+		})
+
+		It("should overwrite the change if it is forced", func() {
+			Expect(cf.ForceSave(cfg)).To(Succeed())
+			b, _ := os.ReadFile(testFile)
+			Expect(string(b)).NotTo(ContainSubstring("default: changed"))
+		})
+	})
+
+	When("config file is saved by itself", func() {
+		It("should be able to save again", func() {
+			Expect(cf.Save(cfg)).To(Succeed())
+			Expect(cf.Save(cfg)).To(Succeed())
+		})
+	})
+})
+
+var _ = Describe("Read", func() {
+	var cf *ConfigFile
+
+	BeforeEach(func() {
+		var err error
+		cf, err = NewConfigFile(filepath.Join(GinkgoT().TempDir(), "config.yaml"))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return fresh snapshot that is not shared", func() {
+		c1, _ := cf.Read()
+		c1.SetDefault("changed")
+		c2, err := cf.Read()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c2.Default).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Update", func() {
+	var (
+		testFile string
+		cf       *ConfigFile
+	)
+
+	BeforeEach(func() {
+		var err error
+		testFile = filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		cf, err = NewConfigFile(testFile)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	When("config file is changed by other process before update", func() {
+		It("should keep the change", func() {
+			os.WriteFile(testFile, []byte("default: changed\nprofiles: {}\n"), 0600)
+			Expect(cf.Update(func(c *Config) error {
+				return c.SetProfile("new", Profile{Env: Envs{{Name: "VAR", Value: "VAL"}}})
+			})).To(Succeed())
+			c, _ := cf.Read()
+			Expect(c.Default).To(Equal("changed"))
+			Expect(c.ProfileNames()).To(ContainElement("new"))
+		})
+	})
+
+	When("update function returns error", func() {
+		It("should not save the change", func() {
+			err := cf.Update(func(c *Config) error {
+				c.SetDefault("changed")
+				return errors.New("failed")
+			})
+			Expect(err).To(MatchError("failed"))
+			c, _ := cf.Read()
+			Expect(c.Default).To(BeEmpty())
+		})
+	})
+
+	When("updated config is not valid", func() {
+		It("should not save the change", func() {
+			err := cf.Update(func(c *Config) error {
+				c.Profiles.SetProfile("a", Profile{Extends: []string{"b"}})
+				c.Profiles.SetProfile("b", Profile{Extends: []string{"a"}})
+				return nil
+			})
+			Expect(err).To(HaveOccurred())
+			c, _ := cf.Read()
+			Expect(c.ProfileNames()).To(BeEmpty())
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/sunggun-yu/envp/internal/config"
 	"gopkg.in/yaml.v3"
@@ -82,3 +83,21 @@ func TestProfile(t *testing.T) {
 		}
 	})
 }
+
+func TestEvaluationSettings(t *testing.T) {
+	data := `
+expansion: legacy
+value-timeout: 10s
+total-timeout: 1m
+`
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	if !cfg.LegacyExpansion() {
+		t.Error("legacy expansion should be true")
+	}
+	if cfg.ValueTimeout != 10*time.Second || cfg.TotalTimeout != time.Minute {
+		t.Error("Not meet expectation", cfg.ValueTimeout, cfg.TotalTimeout)
+	}
+}
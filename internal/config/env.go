@@ -2,20 +2,200 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"regexp"
 	"sort"
 	"strings"
+	"time"
+
+	"github.com/sunggun-yu/envp/internal/secret"
+)
+
+// operations of Env against the environment variable that is existing already
+const (
+	EnvOpSet     = "set"     // set the value. it overwrites existing value
+	EnvOpPrepend = "prepend" // prepend the value to existing value with separator
+	EnvOpAppend  = "append"  // append the value to existing value with separator
+	EnvOpUnset   = "unset"   // unset the environment variable
 )
 
+// MaskedValue is printed instead of the value of secret env var
+const MaskedValue = "********"
+
 // Env represent environment variable name and value
 // go yaml doesn't support capitalized key. so follow k8s env format
 type Env struct {
-	Name  string `mapstructure:"name" yaml:"name"`
-	Value string `mapstructure:"value" yaml:"value"`
+	Name      string `mapstructure:"name" yaml:"name"`
+	Value     string `mapstructure:"value" yaml:"value,omitempty"`
+	Op        string `mapstructure:"op" yaml:"op,omitempty"`
+	Separator string `mapstructure:"separator" yaml:"separator,omitempty"`
+	// ValueFrom is source of the value such as file, env var, command and provider. Value is ignored if it is set
+	ValueFrom *ValueFrom `mapstructure:"value-from" yaml:"value-from,omitempty"`
+	// Cache is ttl to cache the evaluated value. it is useful for expensive command substitution such as credential commands
+	Cache time.Duration `mapstructure:"cache" yaml:"cache,omitempty"`
+	// Secret masks the value wherever envp prints it
+	Secret bool `mapstructure:"secret" yaml:"secret,omitempty"`
+}
+
+// EnvOpNotSupportedError is an error when op of Env is not supported
+type EnvOpNotSupportedError struct {
+	name string
+	op   string
+}
+
+// NewEnvOpNotSupportedError create new EnvOpNotSupportedError
+func NewEnvOpNotSupportedError(name, op string) *EnvOpNotSupportedError {
+	return &EnvOpNotSupportedError{
+		name: name,
+		op:   op,
+	}
+}
+
+// Error is to make EnvOpNotSupportedError errors
+func (e *EnvOpNotSupportedError) Error() string {
+	return fmt.Sprintf("op %s of %s is not supported. it must be one of %s, %s, %s, %s", e.op, e.name, EnvOpSet, EnvOpPrepend, EnvOpAppend, EnvOpUnset)
 }
 
-// Override String() to make it KEY=VAL format
+// EnvFlagInvalidError is an error when env flag is not VAR=VAL format
+type EnvFlagInvalidError struct {
+	flag string
+}
+
+// NewEnvFlagInvalidError create new EnvFlagInvalidError
+func NewEnvFlagInvalidError(flag string) *EnvFlagInvalidError {
+	return &EnvFlagInvalidError{
+		flag: flag,
+	}
+}
+
+// Error is to make EnvFlagInvalidError errors
+func (e *EnvFlagInvalidError) Error() string {
+	return fmt.Sprintf("env %q must be VAR=VAL format", e.flag)
+}
+
+// Override String() to make it KEY=VAL format. value is masked if it is secret
 func (e Env) String() string {
-	return fmt.Sprint(e.Name, "=", e.Value)
+	return fmt.Sprint(e.Name, "=", e.displayValue())
+}
+
+// IsSecret checks if the value must be masked. encrypted value is always secret
+func (e Env) IsSecret() bool {
+	return e.Secret || secret.IsEncrypted(e.Value)
+}
+
+// displayValue returns MaskedValue if Env is secret. otherwise value
+func (e Env) displayValue() string {
+	if e.IsSecret() {
+		return MaskedValue
+	}
+	return e.Value
+}
+
+// Operation returns op of Env. it is "set" if op is empty
+func (e Env) Operation() string {
+	if e.Op == "" {
+		return EnvOpSet
+	}
+	return e.Op
+}
+
+// Sep returns separator for prepend and append op. it is os path list separator(":") if separator is empty
+func (e Env) Sep() string {
+	if e.Separator == "" {
+		return string(os.PathListSeparator)
+	}
+	return e.Separator
+}
+
+// Validate checks if op and value-from of Env are valid
+func (e Env) Validate() error {
+	switch e.Operation() {
+	case EnvOpSet, EnvOpPrepend, EnvOpAppend, EnvOpUnset:
+	default:
+		return NewEnvOpNotSupportedError(e.Name, e.Op)
+	}
+	if e.ValueFrom != nil {
+		return e.ValueFrom.Validate(e.Name)
+	}
+	return nil
+}
+
+// Expression returns the expression that the value is evaluated from. it is source of value-from if it is set, otherwise the value
+func (e Env) Expression() string {
+	if e.ValueFrom != nil {
+		return e.ValueFrom.String()
+	}
+	return e.Value
+}
+
+// EnvReferenceCycleError is an error when env vars are referring each other
+type EnvReferenceCycleError struct {
+	names []string
+}
+
+// NewEnvReferenceCycleError create new EnvReferenceCycleError
+func NewEnvReferenceCycleError(names []string) *EnvReferenceCycleError {
+	return &EnvReferenceCycleError{
+		names: names,
+	}
+}
+
+// Error is to make EnvReferenceCycleError errors
+func (e *EnvReferenceCycleError) Error() string {
+	return fmt.Sprintf("env vars have cycle in references: %s", strings.Join(e.names, ", "))
+}
+
+// referencePattern matches $VAR and ${VAR...} in the value. `$$` and escaped `\$` are matched as well to skip them
+var referencePattern = regexp.MustCompile(`\\\$|\$\$|\$\{?([A-Za-z_][A-Za-z0-9_]*)`)
+
+// References returns names of env vars that are referred in the value as $VAR or ${VAR} in order without duplication.
+// references in the command substitution $(...) and file path of value-from are included as well
+func (e Env) References() []string {
+	s := e.Value
+	if e.ValueFrom != nil {
+		s = e.ValueFrom.File
+	}
+	var r []string
+	seen := map[string]bool{}
+	for _, m := range referencePattern.FindAllStringSubmatch(s, -1) {
+		name := m[1]
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		r = append(r, name)
+	}
+	return r
+}
+
+// Statement returns shell statement of Env that represents its op. e.g.
+//
+//	set:     NAME=VAL
+//	prepend: PATH=/some/bin${PATH:+:$PATH}
+//	append:  NO_PROXY=${NO_PROXY:+$NO_PROXY,}.some.domain
+//	unset:   unset NAME
+//
+// value is masked if it is secret. use RevealedStatement to get the statement with actual value
+func (e Env) Statement() string {
+	return e.statement(e.displayValue())
+}
+
+// RevealedStatement is same as Statement but value of secret is not masked
+func (e Env) RevealedStatement() string {
+	return e.statement(e.Value)
+}
+
+// statement returns shell statement of Env with value
+func (e Env) statement(value string) string {
+	switch e.Operation() {
+	case EnvOpPrepend:
+		return fmt.Sprintf("%s=%s${%s:+%s$%s}", e.Name, value, e.Name, e.Sep(), e.Name)
+	case EnvOpAppend:
+		return fmt.Sprintf("%s=${%s:+$%s%s}%s", e.Name, e.Name, e.Name, e.Sep(), value)
+	case EnvOpUnset:
+		return fmt.Sprint("unset ", e.Name)
+	}
+	return fmt.Sprint(e.Name, "=", value)
 }
 
 // Envs is slice of Env
@@ -45,6 +225,105 @@ func (e Envs) String() string {
 	return r
 }
 
+// mergeEnvs merges override into base and returns new Envs. Env items are copied.
+// set and unset of override replaces the items of base that has same name. order of base is kept and new items of override are appended.
+// prepend and append of override are always appended so that they are applied on top of the value of base.
+func mergeEnvs(base, override Envs) Envs {
+	r := Envs{}
+	for _, e := range append(append(Envs{}, base...), override...) {
+		if e == nil {
+			continue
+		}
+		c := *e
+		if op := c.Operation(); op == EnvOpPrepend || op == EnvOpAppend {
+			r = append(r, &c)
+			continue
+		}
+		replaced := false
+		for i := 0; i < len(r); i++ {
+			if r[i].Name != c.Name {
+				continue
+			}
+			if !replaced {
+				r[i] = &c
+				replaced = true
+				continue
+			}
+			r = append(r[:i], r[i+1:]...)
+			i--
+		}
+		if !replaced {
+			r = append(r, &c)
+		}
+	}
+	return r
+}
+
+// EvaluationLevels returns Envs grouped by level of references. env vars in same level have no dependency on each other,
+// so that they can be evaluated concurrently once all the env vars of previous levels are evaluated.
+// declaration order is kept within the level.
+// reference to its own name is considered as reference to the previous value of same name. e.g. PATH=$HOME/bin:$PATH
+func (e Envs) EvaluationLevels() ([]Envs, error) {
+	deps := e.dependencies()
+
+	var levels []Envs
+	done := make([]bool, len(e))
+	count := 0
+	for count < len(e) {
+		level := Envs{}
+		ready := []int{}
+		for i := range e {
+			if done[i] {
+				continue
+			}
+			r := true
+			for j := range deps[i] {
+				if !done[j] {
+					r = false
+					break
+				}
+			}
+			if r {
+				ready = append(ready, i)
+				level = append(level, e[i])
+			}
+		}
+		if len(ready) == 0 {
+			// rest of env vars are in the cycle or depending on it
+			names := []string{}
+			for i, env := range e {
+				if !done[i] {
+					names = append(names, env.Name)
+				}
+			}
+			return nil, NewEnvReferenceCycleError(names)
+		}
+		for _, i := range ready {
+			done[i] = true
+		}
+		count += len(ready)
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// dependencies returns dependencies of env vars by references. deps[i] is index of env vars that e[i] depends on
+func (e Envs) dependencies() []map[int]bool {
+	deps := make([]map[int]bool, len(e))
+	for i, env := range e {
+		deps[i] = map[int]bool{}
+		for _, ref := range env.References() {
+			for j, other := range e {
+				if other.Name != ref || i == j || (ref == env.Name && j > i) {
+					continue
+				}
+				deps[i][j] = true
+			}
+		}
+	}
+	return deps
+}
+
 // ParseEnvFlagToMap parse string format "env=val" to map "env: val". it can be used fo dup check from slice of Env
 func ParseEnvFlagToMap(envs []string) map[string]string {
 
@@ -66,29 +345,24 @@ func ParseEnvFlagToMap(envs []string) map[string]string {
 	return r
 }
 
-// ParseEnvFlagToEnv parse slice of string "var=val" to []ENV
-func ParseEnvFlagToEnv(args []string) Envs {
+// ParseEnvFlagToEnv parse slice of string "var=val" to []ENV. order of args is kept.
+// value is everything after the first "=", so it can contain "=" as well
+func ParseEnvFlagToEnv(args []string) (Envs, error) {
+
 	if len(args) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	r := []*Env{}
 
 	for _, s := range args {
-		ev := strings.Split(s, "=")
-		if len(ev) != 2 {
-			// TODO: handle unexpected format
-			//fmt.Println("WARN: wrong format of env item. it must be var=val.", ev, "will be ignored")
-			continue
-		} else {
-			r = append(r, &Env{
-				Name:  ev[0],
-				Value: ev[1],
-			})
+		name, value, ok := strings.Cut(s, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, NewEnvFlagInvalidError(s)
 		}
+		r = append(r, &Env{Name: name, Value: value})
 	}
-	SortEnv(r)
-	return r
+	return r, nil
 }
 
 // MapToEnv parse string map to slice of Env
@@ -77,22 +77,47 @@ func TestParseEnvFlagToEnv(t *testing.T) {
 
 	t.Run("when set empty data", func(t *testing.T) {
 		// nil data test
-		if ParseEnvFlagToEnv([]string{}) != nil {
-			t.Error("Not meet expectation. empty slice should return nil")
-		}
+		actual, err := ParseEnvFlagToEnv([]string{})
+		assert.NoError(t, err)
+		assert.Nil(t, actual, "empty slice should return nil")
 	})
 
 	t.Run("when data exist", func(t *testing.T) {
-		testData := testDataArrStringFromFlag
-		// invalid format should be ignored without error
-		actual := ParseEnvFlagToEnv(testData)
+		actual, err := ParseEnvFlagToEnv([]string{"VAR_A=VAL_A", "VAR_B=VAL_B", "VAR_C=VAL_C", "VAR_D=VAL_D"})
+		assert.NoError(t, err)
 		expected := testDataEnvs
-		// ParseEnvFlagToEnv sort the result. so expected should be sorted
+		// items of test data are declared in sorted order
 		SortEnv(expected)
 		if !reflect.DeepEqual(expected, actual) {
 			t.Error("Not meet expectation", expected, "-", actual)
 		}
 	})
+
+	t.Run("should keep the order of args", func(t *testing.T) {
+		actual, err := ParseEnvFlagToEnv([]string{"VAR_B=VAL_B", "VAR_A=VAL_A"})
+		assert.NoError(t, err)
+		assert.Equal(t, "VAR_B=VAL_B,VAR_A=VAL_A", actual.String())
+	})
+
+	t.Run("should keep the value that contains =", func(t *testing.T) {
+		actual, err := ParseEnvFlagToEnv([]string{"TOKEN=abc==", "URL=http://x?a=b&c=d", "how=about=this", "EMPTY="})
+		assert.NoError(t, err)
+		assert.Equal(t, Envs{
+			&Env{Name: "TOKEN", Value: "abc=="},
+			&Env{Name: "URL", Value: "http://x?a=b&c=d"},
+			&Env{Name: "how", Value: "about=this"},
+			&Env{Name: "EMPTY", Value: ""},
+		}, actual)
+	})
+
+	t.Run("should return error when arg is not var=val format", func(t *testing.T) {
+		for _, arg := range []string{"something_not_valid", "not:valid", " ", "=value"} {
+			actual, err := ParseEnvFlagToEnv([]string{"VAR_A=VAL_A", arg})
+			assert.Nil(t, actual, arg)
+			var invalid *EnvFlagInvalidError
+			assert.ErrorAs(t, err, &invalid, arg)
+		}
+	})
 }
 
 // test MapToEnv func
@@ -126,3 +151,122 @@ func TestEnvsAddEnv(t *testing.T) {
 
 	assert.ElementsMatch(t, expected, actual.Strings())
 }
+
+// TestEnvStatement tests Statement func in Env
+func TestEnvStatement(t *testing.T) {
+	assert.Equal(t, "VAR=VAL", Env{Name: "VAR", Value: "VAL"}.Statement())
+	assert.Equal(t, "VAR=VAL", Env{Name: "VAR", Value: "VAL", Op: EnvOpSet}.Statement())
+	assert.Equal(t, "PATH=/opt/bin${PATH:+:$PATH}", Env{Name: "PATH", Value: "/opt/bin", Op: EnvOpPrepend}.Statement())
+	assert.Equal(t, "NO_PROXY=${NO_PROXY:+$NO_PROXY,}.corp", Env{Name: "NO_PROXY", Value: ".corp", Op: EnvOpAppend, Separator: ","}.Statement())
+	assert.Equal(t, "unset VAR", Env{Name: "VAR", Op: EnvOpUnset}.Statement())
+}
+
+// TestEnvValidate tests Validate func in Env
+func TestEnvValidate(t *testing.T) {
+	for _, op := range []string{"", EnvOpSet, EnvOpPrepend, EnvOpAppend, EnvOpUnset} {
+		assert.NoError(t, Env{Name: "VAR", Op: op}.Validate())
+	}
+	var opErr *EnvOpNotSupportedError
+	assert.ErrorAs(t, Env{Name: "VAR", Op: "meow"}.Validate(), &opErr)
+}
+
+// TestMergeEnvs tests mergeEnvs func
+func TestMergeEnvs(t *testing.T) {
+	base := Envs{
+		{Name: "PATH", Value: "/usr/bin"},
+		{Name: "NO_PROXY", Value: "localhost"},
+	}
+	override := Envs{
+		{Name: "PATH", Value: "/opt/bin", Op: EnvOpPrepend},
+		{Name: "NO_PROXY", Value: "127.0.0.1"},
+	}
+	actual := mergeEnvs(base, override)
+	assert.Equal(t, "PATH=/usr/bin,NO_PROXY=127.0.0.1,PATH=/opt/bin", actual.String())
+	assert.Equal(t, EnvOpPrepend, actual[2].Op)
+
+	// set should replace the prepend and append as well
+	actual = mergeEnvs(actual, Envs{{Name: "PATH", Value: "/bin"}})
+	assert.Equal(t, "PATH=/bin,NO_PROXY=127.0.0.1", actual.String())
+}
+
+// TestEnvReferences tests References func in Env
+func TestEnvReferences(t *testing.T) {
+	e := Env{Name: "KUBECONFIG", Value: "$BASE/kube/${CLUSTER}:${HOME:-/tmp}/$(echo $SUB)/$1/$$/\\$ESCAPED/$BASE"}
+	assert.Equal(t, []string{"BASE", "CLUSTER", "HOME", "SUB"}, e.References())
+	assert.Empty(t, Env{Name: "VAR", Value: "no reference"}.References())
+}
+
+// TestEnvsEvaluationLevels tests EvaluationLevels func in Envs
+func TestEnvsEvaluationLevels(t *testing.T) {
+
+	t.Run("should group env vars by level of references", func(t *testing.T) {
+		envs := Envs{
+			{Name: "KUBECONFIG", Value: "$BASE/kube"},
+			{Name: "OTHER", Value: "other"},
+			{Name: "BASE", Value: "$ROOT/base"},
+			{Name: "ROOT", Value: "/root"},
+			{Name: "PATH", Value: "$ROOT/bin:$PATH"},
+		}
+		levels, err := envs.EvaluationLevels()
+		assert.NoError(t, err)
+		actual := []string{}
+		for _, l := range levels {
+			actual = append(actual, l.String())
+		}
+		assert.Equal(t, []string{
+			"OTHER=other,ROOT=/root",
+			"BASE=$ROOT/base,PATH=$ROOT/bin:$PATH",
+			"KUBECONFIG=$BASE/kube",
+		}, actual)
+	})
+
+	t.Run("should be error when reference has cycle", func(t *testing.T) {
+		envs := Envs{
+			{Name: "A", Value: "$B"},
+			{Name: "B", Value: "${C}"},
+			{Name: "C", Value: "$(echo $A)"},
+			{Name: "D", Value: "d"},
+		}
+		_, err := envs.EvaluationLevels()
+		var cycleErr *EnvReferenceCycleError
+		assert.ErrorAs(t, err, &cycleErr)
+		assert.Contains(t, err.Error(), "A, B, C")
+	})
+}
+
+// TestValueFrom tests Validate and String func in ValueFrom
+func TestValueFrom(t *testing.T) {
+	assert.NoError(t, (&ValueFrom{File: "~/.token"}).Validate("TOKEN"))
+	assert.Equal(t, "file:~/.token", (&ValueFrom{File: "~/.token"}).String())
+	assert.Equal(t, "command:[cat token]", (&ValueFrom{Command: []string{"cat", "token"}}).String())
+	assert.Equal(t, "provider:vault{a=1,b=2}", (&ValueFrom{Provider: "vault", Params: map[string]string{"b": "2", "a": "1"}}).String())
+
+	var invalidErr *ValueFromInvalidError
+	assert.ErrorAs(t, (&ValueFrom{}).Validate("TOKEN"), &invalidErr)
+	assert.ErrorAs(t, (&ValueFrom{File: "~/.token", Env: "TOKEN"}).Validate("TOKEN"), &invalidErr)
+	assert.ErrorAs(t, Env{Name: "TOKEN", ValueFrom: &ValueFrom{}}.Validate(), &invalidErr)
+
+	assert.NoError(t, (&ValueFrom{Provider: "aws_sm-2"}).Validate("TOKEN"))
+	var providerErr *ProviderNameInvalidError
+	for _, p := range []string{"../bin/vault", "vault/x", "..", "a.b", "vault "} {
+		assert.ErrorAs(t, (&ValueFrom{Provider: p}).Validate("TOKEN"), &providerErr, p)
+	}
+
+	assert.Equal(t, "env:GH_TOKEN", Env{Name: "TOKEN", ValueFrom: &ValueFrom{Env: "GH_TOKEN"}}.Expression())
+}
+
+// TestEnvSecret tests masking of secret Env
+func TestEnvSecret(t *testing.T) {
+	e := Env{Name: "TOKEN", Value: "my-token", Secret: true}
+	assert.True(t, e.IsSecret())
+	assert.Equal(t, "TOKEN="+MaskedValue, e.String())
+	assert.Equal(t, "TOKEN="+MaskedValue, e.Statement())
+	assert.Equal(t, "TOKEN=my-token", e.RevealedStatement())
+	assert.Equal(t, "TOKEN="+MaskedValue+",PLAIN=plain", Envs{&e, {Name: "PLAIN", Value: "plain"}}.String())
+
+	p := Env{Name: "PATH", Value: "/secret/bin", Op: EnvOpPrepend, Secret: true}
+	assert.Equal(t, "PATH="+MaskedValue+"${PATH:+:$PATH}", p.Statement())
+
+	assert.True(t, Env{Name: "TOKEN", Value: "enc:v1:abcd"}.IsSecret())
+	assert.False(t, Env{Name: "TOKEN", Value: "plain"}.IsSecret())
+}
@@ -0,0 +1,226 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// formatIndent is the indentation of formatted config file. sequence is not indented in its parent mapping
+const formatIndent = 2
+
+// Format returns the content of config file in canonical format. keys are ordered as the fields of the types
+// and profiles are sorted by name. init-script is converted to list of run and unnecessary quotes are removed.
+// comments are kept
+func Format(b []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	if len(doc.Content) == 0 {
+		return b, nil
+	}
+	root := doc.Content[0]
+	// comment on top of the file stays on top even though the first key is moved
+	if root.Kind == yaml.MappingNode && len(root.Content) > 0 && doc.HeadComment == "" {
+		doc.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	formatNode(root, reflect.TypeOf(Config{}), false)
+
+	return encodeNode(&doc, formatIndent, true)
+}
+
+// Format formats the content that Config is read from so that Config is saved in canonical format.
+// it returns the content to be saved
+func (c *Config) Format() ([]byte, error) {
+	raw := c.raw
+	if len(raw) == 0 {
+		b, err := yaml.Marshal(c)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+	formatted, err := Format(raw)
+	if err != nil {
+		return nil, err
+	}
+	// init-script of string is converted in the content. Config should have same shape not to be patched back
+	if err := migrateInitScriptToList(c); err != nil {
+		return nil, err
+	}
+	c.raw = formatted
+	return Marshal(c)
+}
+
+// formatNode formats node of type t. flow is whether the node is flow style by yaml tag
+func formatNode(n *yaml.Node, t reflect.Type, flow bool) {
+	if n.Kind == yaml.AliasNode {
+		// anchored node is formatted where it is defined
+		return
+	}
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch n.Kind {
+	case yaml.ScalarNode:
+		if n.Tag == "!!str" && (n.Style == yaml.SingleQuotedStyle || n.Style == yaml.DoubleQuotedStyle) {
+			// encoder quotes the value again if it is needed
+			n.Style = 0
+		}
+		return
+	case yaml.MappingNode, yaml.SequenceNode:
+		n.Style = 0
+		if flow {
+			n.Style = yaml.FlowStyle
+		}
+	}
+
+	switch {
+	case t == reflect.TypeOf(Profiles{}):
+		sortKeys(n, nil)
+		forEachValue(n, func(_ string, v *yaml.Node) {
+			formatNode(v, reflect.TypeOf(Profile{}), false)
+		})
+	case t == reflect.TypeOf(Profile{}) && n.Kind == yaml.MappingNode:
+		fields := fieldsOf(t)
+		sortKeys(n, fields)
+		forEachValue(n, func(k string, v *yaml.Node) {
+			if f, ok := fields[k]; ok {
+				if k == "init-script" {
+					formatInitScript(v)
+				}
+				formatNode(v, f.Type, strings.Contains(f.Tag.Get("yaml"), ",flow"))
+				return
+			}
+			formatNode(v, reflect.TypeOf(Profile{}), false)
+		})
+	case t != nil && t.Kind() == reflect.Struct && n.Kind == yaml.MappingNode:
+		fields := fieldsOf(t)
+		sortKeys(n, fields)
+		forEachValue(n, func(k string, v *yaml.Node) {
+			if f, ok := fields[k]; ok {
+				formatNode(v, f.Type, strings.Contains(f.Tag.Get("yaml"), ",flow"))
+				return
+			}
+			formatNode(v, nil, false)
+		})
+	case t != nil && t.Kind() == reflect.Slice && n.Kind == yaml.SequenceNode:
+		for _, item := range n.Content {
+			formatNode(item, t.Elem(), false)
+		}
+	default:
+		// value of unknown key or map
+		for _, c := range n.Content {
+			var et reflect.Type
+			if t != nil && t.Kind() == reflect.Map {
+				et = t.Elem()
+			}
+			formatNode(c, et, false)
+		}
+	}
+}
+
+// formatInitScript converts init-script of string to list of run. comment of the string is kept
+func formatInitScript(n *yaml.Node) {
+	if n.Kind != yaml.ScalarNode || n.Tag == "!!null" {
+		return
+	}
+	run := *n
+	*n = yaml.Node{
+		Kind: yaml.SequenceNode,
+		Tag:  "!!seq",
+		Content: []*yaml.Node{{
+			Kind: yaml.MappingNode,
+			Tag:  "!!map",
+			Content: []*yaml.Node{
+				{Kind: yaml.ScalarNode, Tag: "!!str", Value: "run"},
+				&run,
+			},
+		}},
+	}
+}
+
+// fieldsOf returns the fields of struct type t by yaml key in order of the fields. inline fields are excluded
+func fieldsOf(t reflect.Type) map[string]reflect.StructField {
+	fields := map[string]reflect.StructField{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" || strings.Contains(opts, "inline") {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f
+	}
+	return fields
+}
+
+// sortKeys sorts the entries of mapping node. merge key comes first, then fields in order of them and the others by name.
+// the others that define anchor stay in order before them so that anchor is defined before its aliases
+func sortKeys(n *yaml.Node, fields map[string]reflect.StructField) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	entries := make([][2]*yaml.Node, 0, len(n.Content)/2)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		entries = append(entries, [2]*yaml.Node{n.Content[i], n.Content[i+1]})
+	}
+	rank := func(e [2]*yaml.Node) int {
+		if e[0].Value == "<<" {
+			return -1
+		}
+		if f, ok := fields[e[0].Value]; ok {
+			return f.Index[0]
+		}
+		if hasAnchor(e[1]) {
+			return len(n.Content)
+		}
+		return len(n.Content) + 1
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := rank(entries[i]), rank(entries[j])
+		if a != b {
+			return a < b
+		}
+		return a > len(n.Content) && entries[i][0].Value < entries[j][0].Value
+	})
+	n.Content = n.Content[:0]
+	for _, e := range entries {
+		n.Content = append(n.Content, e[0], e[1])
+	}
+}
+
+// hasAnchor returns whether the node or its descendants define anchor
+func hasAnchor(n *yaml.Node) bool {
+	if n.Anchor != "" {
+		return true
+	}
+	for _, c := range n.Content {
+		if hasAnchor(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// forEachValue calls fn with key and value of the entries of mapping node except for merge key
+func forEachValue(n *yaml.Node, fn func(key string, value *yaml.Node)) {
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k := n.Content[i]
+		if k.Value == "<<" {
+			// encoder writes the merge tag explicitly if it is set
+			k.Tag = ""
+			continue
+		}
+		fn(k.Value, n.Content[i+1])
+	}
+}
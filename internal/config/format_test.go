@@ -0,0 +1,97 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Format", func() {
+	raw, _ := os.ReadFile("testdata/roundtrip.yaml")
+
+	It("should format config file canonically", func() {
+		b, err := Format(raw)
+		Expect(err).NotTo(HaveOccurred())
+		file := filepath.Join("testdata", "golden", "format.yaml")
+		if *updateGolden {
+			Expect(os.WriteFile(file, b, 0644)).To(Succeed())
+		}
+		expected, err := os.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(string(expected)))
+	})
+
+	It("should not change formatted content", func() {
+		b, _ := Format(raw)
+		again, err := Format(b)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(again)).To(Equal(string(b)))
+	})
+
+	It("should order keys, normalize init-script and quotes and keep comments", func() {
+		b, err := Format([]byte(`# my envp config
+profiles:
+    # zeta is for z
+    z:
+        env:
+            - value: "123"
+              name: 'A'
+            - name: B
+              value: "plain"
+    a:
+      init-script: echo hi # run it
+      extends: [z]
+      desc: "a: b"
+default: a
+`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`# my envp config
+
+default: a
+profiles:
+  a:
+    desc: 'a: b'
+    init-script:
+    - run: echo hi # run it
+    extends:
+    - z
+  # zeta is for z
+  z:
+    env:
+    - name: A
+      value: "123"
+    - name: B
+      value: plain
+`))
+	})
+
+	It("should keep anchors and merge keys", func() {
+		b, err := Format([]byte("profiles:\n  z: &z\n    env:\n    - name: A\n      value: a\n  c:\n    desc: c\n  b:\n    desc: b\n    <<: *z\n"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal("profiles:\n  z: &z\n    env:\n    - name: A\n      value: a\n  b:\n    <<: *z\n    desc: b\n  c:\n    desc: c\n"))
+	})
+
+	When("Config is formatted", func() {
+		It("should be saved in the format", func() {
+			testFile := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+			Expect(os.WriteFile(testFile, raw, 0600)).To(Succeed())
+			cf, err := NewConfigFile(testFile)
+			Expect(err).NotTo(HaveOccurred())
+
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			b, err := cfg.Format()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(b)).To(HavePrefix("# envp config for testing\n\nversion: 2\ndefault: docker\n"))
+			Expect(cf.Save(cfg)).To(Succeed())
+
+			saved, _ := os.ReadFile(testFile)
+			Expect(string(saved)).To(Equal(string(b)))
+			cfg, _ = cf.Read()
+			again, _ := cfg.Format()
+			Expect(string(again)).To(Equal(string(b)))
+		})
+	})
+})
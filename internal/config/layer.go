@@ -0,0 +1,344 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// layers of config files in order of precedence. closer layer to the user wins
+const (
+	LayerSystem  = "system"  // org-wide profiles
+	LayerUser    = "user"    // personal profiles. it is the config file of ConfigFile
+	LayerProject = "project" // repository specific profiles
+)
+
+// SystemConfigFile is the config file of system layer
+const SystemConfigFile = "/etc/envp/config.yaml"
+
+// ProjectConfigFile is the file name of project layer. it is found by walking up from current directory
+const ProjectConfigFile = ".envp.yaml"
+
+// LayerNotExistingError is an error when the layer to write is not existing
+type LayerNotExistingError struct {
+	layer string
+}
+
+// NewLayerNotExistingError create new LayerNotExistingError
+func NewLayerNotExistingError(layer string) *LayerNotExistingError {
+	return &LayerNotExistingError{
+		layer: layer,
+	}
+}
+
+// Error is to make LayerNotExistingError errors
+func (e *LayerNotExistingError) Error() string {
+	switch e.layer {
+	case LayerProject:
+		return fmt.Sprintf("config file of project layer is not found. create %s in the project directory", ProjectConfigFile)
+	case LayerSystem, LayerUser:
+		return fmt.Sprintf("config file of %s layer is not set", e.layer)
+	}
+	return fmt.Sprintf("layer %s is not supported. it must be one of %s, %s, %s", e.layer, LayerSystem, LayerUser, LayerProject)
+}
+
+// ProjectSettingNotAllowedError is an error when the config of project layer has the setting that only system and user layers can have
+type ProjectSettingNotAllowedError struct {
+	setting string
+}
+
+// NewProjectSettingNotAllowedError create new ProjectSettingNotAllowedError
+func NewProjectSettingNotAllowedError(setting string) *ProjectSettingNotAllowedError {
+	return &ProjectSettingNotAllowedError{
+		setting: setting,
+	}
+}
+
+// Error is to make ProjectSettingNotAllowedError errors
+func (e *ProjectSettingNotAllowedError) Error() string {
+	return fmt.Sprintf("%s cannot be set in config file of project layer. set it in config file of system or user layer", e.setting)
+}
+
+// ConfigMergedError is an error when Config that is merged from the layers is saved
+type ConfigMergedError struct{}
+
+// NewConfigMergedError create new ConfigMergedError
+func NewConfigMergedError() *ConfigMergedError {
+	return &ConfigMergedError{}
+}
+
+// Error is to make ConfigMergedError errors
+func (e *ConfigMergedError) Error() string {
+	return "config that is merged from the layers cannot be saved. use Update to change the config file of the layer"
+}
+
+// FindProjectConfigFile returns the path of project config file in dir or its parents. it is empty if it is not found
+func FindProjectConfigFile(dir string) string {
+	for {
+		f := filepath.Join(dir, ProjectConfigFile)
+		if fi, err := os.Stat(f); err == nil && !fi.IsDir() {
+			return f
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// SetLayers sets the config files of system and project layers. Read merges them with the config file, that is user layer.
+// empty name is ignored and not existing file is skipped when it is read
+func (c *ConfigFile) SetLayers(system, project string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.layers = nil
+	for _, l := range []struct{ layer, name string }{
+		{LayerSystem, system},
+		{LayerUser, c.name},
+		{LayerProject, project},
+	} {
+		if l.name == "" {
+			continue
+		}
+		lf := &ConfigFile{
+			name:       l.name,
+			maxBackups: c.maxBackups,
+			layer:      l.layer,
+		}
+		lf.validate = func(cfg *Config) error {
+			if lf.layer == LayerProject {
+				if err := validateProjectSettings(cfg); err != nil {
+					return err
+				}
+			}
+			merged, err := c.readMerged(lf, cfg)
+			if err != nil {
+				return err
+			}
+			return merged.Validate()
+		}
+		c.layers = append(c.layers, lf)
+	}
+	c.target = c.layerFile(LayerUser)
+}
+
+// SetWriteLayer sets the layer that Update and Save write to. it is user layer by default
+func (c *ConfigFile) SetWriteLayer(layer string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.layers) == 0 && layer == LayerUser {
+		return nil
+	}
+	lf := c.layerFile(layer)
+	if lf == nil {
+		return NewLayerNotExistingError(layer)
+	}
+	c.target = lf
+	return nil
+}
+
+// Target returns ConfigFile of the layer that changes are written to. it is the file itself if the layers are not set
+func (c *ConfigFile) Target() *ConfigFile {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if c.target == nil {
+		return c
+	}
+	return c.target
+}
+
+// layerFile returns ConfigFile of the layer
+func (c *ConfigFile) layerFile(layer string) *ConfigFile {
+	for _, lf := range c.layers {
+		if lf.layer == layer {
+			return lf
+		}
+	}
+	return nil
+}
+
+// readMerged reads the existing config files of the layers and merges them. cfg is used for target instead of reading it.
+// Config of the file itself is returned as it is if the other layers are not existing
+func (c *ConfigFile) readMerged(target *ConfigFile, cfg *Config) (*Config, error) {
+	files := []*ConfigFile{}
+	configs := []*Config{}
+	for _, lf := range c.layers {
+		if lf == target {
+			files, configs = append(files, lf), append(configs, cfg)
+			continue
+		}
+		if _, err := os.Stat(lf.name); os.IsNotExist(err) {
+			continue
+		}
+		lc, err := lf.Read()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read config file of %s layer %s: %w", lf.layer, lf.name, err)
+		}
+		files, configs = append(files, lf), append(configs, lc)
+	}
+	if len(configs) == 1 && files[0].layer == LayerUser {
+		return configs[0], nil
+	}
+	return mergeConfigs(files, configs), nil
+}
+
+// hashLayers returns hash of the existing config files of the layers
+func (c *ConfigFile) hashLayers() (string, error) {
+	hashes := []string{}
+	for _, lf := range c.layers {
+		b, err := os.ReadFile(lf.name)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		hashes = append(hashes, hashOf(b))
+	}
+	return hashOf([]byte(strings.Join(hashes, "\n"))), nil
+}
+
+// validateProjectSettings validates that config of project layer has no setting that only system and user layers can have.
+// project config file is found from current directory, so it must not change default profile and the policies of user
+func validateProjectSettings(cfg *Config) error {
+	switch {
+	case cfg.Default != "":
+		return NewProjectSettingNotAllowedError("default")
+	case cfg.Bundle != nil:
+		return NewProjectSettingNotAllowedError("bundle")
+	case cfg.Audit != nil:
+		return NewProjectSettingNotAllowedError("audit")
+	case cfg.Expansion != "":
+		return NewProjectSettingNotAllowedError("expansion")
+	}
+	return nil
+}
+
+// mergeConfigs merges Config of the layers in order of precedence. setting and profile fields of closer layer win,
+// and child profiles are merged recursively. default, expansion, bundle and audit of project layer are ignored,
+// and signature of bundle is required if any layer requires it
+func mergeConfigs(files []*ConfigFile, configs []*Config) *Config {
+	merged := &Config{
+		Version:  CurrentVersion,
+		Profiles: &Profiles{},
+		sources:  map[string]string{},
+		projects: map[string]bool{},
+	}
+	for i, cfg := range configs {
+		if files[i].layer != LayerProject {
+			if cfg.Default != "" {
+				merged.Default = cfg.Default
+			}
+			if cfg.Expansion != "" {
+				merged.Expansion = cfg.Expansion
+			}
+			if cfg.Audit != nil {
+				merged.Audit = cfg.Audit
+			}
+			merged.Bundle = mergeBundle(merged.Bundle, cfg.Bundle)
+		}
+		if cfg.ValueTimeout != 0 {
+			merged.ValueTimeout = cfg.ValueTimeout
+		}
+		if cfg.TotalTimeout != 0 {
+			merged.TotalTimeout = cfg.TotalTimeout
+		}
+		if cfg.Profiles == nil {
+			continue
+		}
+		mergeProfiles(*merged.Profiles, *cfg.Profiles)
+		cfg.Profiles.Walk(func(name string, p *Profile) {
+			// group is from the layer that defines its own fields rather than the layer that only has its children
+			if _, ok := merged.sources[name]; !ok || !p.isGroupOnly() {
+				merged.sources[name] = files[i].name
+			}
+			if files[i].layer == LayerProject && !p.isGroupOnly() {
+				merged.projects[name] = true
+			}
+		})
+	}
+	return merged
+}
+
+// mergeBundle merges bundle config of closer layer src into dst. closer layer cannot turn off require-signature of the others,
+// and trusted keys of all the layers are trusted
+func mergeBundle(dst, src *BundleConfig) *BundleConfig {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &BundleConfig{}
+	}
+	return &BundleConfig{
+		RequireSignature: dst.RequireSignature || src.RequireSignature,
+		TrustedKeys:      append(append([]TrustedKey{}, dst.TrustedKeys...), src.TrustedKeys...),
+	}
+}
+
+// mergeProfiles merges profiles of closer layer src into dst
+func mergeProfiles(dst, src Profiles) {
+	for name, p := range src {
+		if p == nil {
+			continue
+		}
+		d := dst[name]
+		if d == nil {
+			d = &Profile{}
+			dst[name] = d
+		}
+		if p.Desc != "" {
+			d.Desc = p.Desc
+		}
+		if len(p.Env) > 0 {
+			d.Env = p.Env
+		}
+		if p.InitScript != nil {
+			d.InitScript = p.InitScript
+		}
+		if p.Inherit != nil {
+			d.Inherit = p.Inherit
+		}
+		if len(p.Extends) > 0 {
+			d.Extends = p.Extends
+		}
+		if len(p.Profiles) > 0 {
+			if d.Profiles == nil {
+				d.Profiles = Profiles{}
+			}
+			mergeProfiles(d.Profiles, p.Profiles)
+		}
+	}
+}
+
+// isGroupOnly returns whether the profile has only child profiles
+func (p *Profile) isGroupOnly() bool {
+	return p.Desc == "" && len(p.Env) == 0 && p.InitScript == nil && p.Inherit == nil && len(p.Extends) == 0
+}
+
+// Source returns the config file that the profile came from. it is empty if Config is not merged from the layers
+func (c *Config) Source(name string) string {
+	return c.sources[name]
+}
+
+// FromProject returns whether the resolved profile has fields from the config file of project layer.
+// fields of parent groups and extended profiles that the profile is resolved from are considered as well
+func (c *Config) FromProject(name string) bool {
+	if len(c.projects) == 0 || c.Profiles == nil {
+		return false
+	}
+	r := newProfileResolver(c.Profiles)
+	if err := r.resolve(name); err != nil {
+		// profile that cannot be resolved doesn't run
+		return false
+	}
+	for _, n := range r.names {
+		if c.projects[n] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Layers", func() {
+	var (
+		dir, system, user, project string
+		cf                         *ConfigFile
+	)
+
+	write := func(name, content string) {
+		Expect(os.WriteFile(name, []byte(content), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+		system = filepath.Join(dir, "etc", "config.yaml")
+		user = filepath.Join(dir, "user", "config.yaml")
+		project = filepath.Join(dir, "repo", ProjectConfigFile)
+		os.MkdirAll(filepath.Dir(system), 0755)
+		os.MkdirAll(filepath.Dir(project), 0755)
+
+		write(system, `default: org
+profiles:
+  org:
+    desc: org
+    env:
+    - name: PROXY
+      value: org-proxy
+  shared:
+    desc: from system
+    env:
+    - name: VAR
+      value: system
+`)
+		var err error
+		cf, err = NewConfigFile(user)
+		Expect(err).NotTo(HaveOccurred())
+		write(user, `version: 2
+default: mine
+profiles:
+  mine:
+    extends: [org]
+    env:
+    - name: VAR
+      value: user
+  shared:
+    env:
+    - name: VAR
+      value: user
+`)
+		write(project, `profiles:
+  shared:
+    env:
+    - name: VAR
+      value: project
+  repo:
+    env:
+    - name: VAR
+      value: repo
+`)
+		cf.SetLayers(system, project)
+	})
+
+	When("config is read", func() {
+		It("should merge the layers and closer layer wins", func() {
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Default).To(Equal("mine"))
+			Expect(cfg.ProfileNames()).To(Equal([]string{"mine", "org", "repo", "shared"}))
+
+			shared, _ := cfg.Profile("shared")
+			Expect(shared.Desc).To(Equal("from system"))
+			Expect(shared.Env[0].Value).To(Equal("project"))
+
+			mine, err := cfg.ResolvedProfile("mine")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mine.Env.String()).To(ContainSubstring("PROXY=org-proxy"))
+		})
+
+		It("should tell the file that the profile came from", func() {
+			cfg, _ := cf.Read()
+			Expect(cfg.Source("org")).To(Equal(system))
+			Expect(cfg.Source("mine")).To(Equal(user))
+			Expect(cfg.Source("shared")).To(Equal(project))
+			Expect(cfg.Source("repo")).To(Equal(project))
+		})
+
+		It("should ignore default, expansion, bundle and audit of project layer", func() {
+			write(project, "default: repo\nexpansion: legacy\nbundle:\n  trusted-keys:\n  - name: repo\n    public-key: key\naudit:\n  allowlist:\n  - name: VAR\nprofiles:\n  repo:\n    env:\n    - name: VAR\n      value: repo\n")
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Default).To(Equal("mine"))
+			Expect(cfg.LegacyExpansion()).To(BeFalse())
+			Expect(cfg.Bundle).To(BeNil())
+			Expect(cfg.Audit).To(BeNil())
+		})
+
+		It("should tell whether the resolved profile has fields from project layer", func() {
+			write(project, "profiles:\n  org:\n    child:\n      desc: child\n  shared:\n    env:\n    - name: VAR\n      value: project\n  repo:\n    extends: [mine]\n")
+			write(user, "version: 2\nprofiles:\n  mine:\n    extends: [shared]\n    env:\n    - name: VAR\n      value: user\n  other:\n    extends: [org]\n    env:\n    - name: VAR\n      value: other\n")
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.FromProject("shared")).To(BeTrue())
+			Expect(cfg.FromProject("repo")).To(BeTrue())
+			// extended profile has env from project layer
+			Expect(cfg.FromProject("mine")).To(BeTrue())
+			// project layer has only the child of org
+			Expect(cfg.FromProject("org")).To(BeFalse())
+			Expect(cfg.FromProject("other")).To(BeFalse())
+			// parent group is inherited
+			Expect(cfg.FromProject("org.child")).To(BeTrue())
+		})
+
+		It("should require signature of bundle if any layer requires it", func() {
+			write(system, "bundle:\n  require-signature: true\n  trusted-keys:\n  - name: org\n    public-key: org-key\nprofiles: {}\n")
+			write(user, "version: 2\nbundle:\n  require-signature: false\n  trusted-keys:\n  - name: mine\n    public-key: my-key\nprofiles: {}\n")
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Bundle.RequireSignature).To(BeTrue())
+			Expect(cfg.Bundle.TrustedKeys).To(Equal([]TrustedKey{{Name: "org", PublicKey: "org-key"}, {Name: "mine", PublicKey: "my-key"}}))
+		})
+
+		It("should not be saved as it is merged", func() {
+			cfg, _ := cf.Read()
+			var merged *ConfigMergedError
+			Expect(errors.As(cf.Save(cfg), &merged)).To(BeTrue())
+		})
+
+		It("should be same as user config if the other layers are not existing", func() {
+			os.Remove(system)
+			os.Remove(project)
+			write(user, "version: 2\ndefault: mine\nprofiles:\n  mine:\n    env:\n    - name: VAR\n      value: user\n")
+			cfg, err := cf.Read()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cfg.Source("mine")).To(BeEmpty())
+			Expect(cf.Save(cfg)).To(Succeed())
+		})
+	})
+
+	When("config is updated", func() {
+		It("should write to user layer by default", func() {
+			Expect(cf.Update(func(c *Config) error {
+				return c.SetProfile("new", Profile{Env: Envs{{Name: "VAR", Value: "new"}}})
+			})).To(Succeed())
+			b, _ := os.ReadFile(user)
+			Expect(string(b)).To(ContainSubstring("new:"))
+			b, _ = os.ReadFile(project)
+			Expect(string(b)).NotTo(ContainSubstring("new:"))
+		})
+
+		It("should write to the chosen layer", func() {
+			Expect(cf.SetWriteLayer(LayerProject)).To(Succeed())
+			Expect(cf.Target().Name()).To(Equal(project))
+			Expect(cf.Update(func(c *Config) error {
+				return c.SetProfile("new", Profile{Env: Envs{{Name: "VAR", Value: "new"}}})
+			})).To(Succeed())
+			b, _ := os.ReadFile(project)
+			Expect(string(b)).To(ContainSubstring("new:"))
+		})
+
+		It("should refuse the settings that project layer cannot have", func() {
+			Expect(cf.SetWriteLayer(LayerProject)).To(Succeed())
+			var notAllowed *ProjectSettingNotAllowedError
+			Expect(errors.As(cf.Update(func(c *Config) error {
+				c.SetDefault("repo")
+				return nil
+			}), &notAllowed)).To(BeTrue())
+			Expect(errors.As(cf.Update(func(c *Config) error {
+				c.Bundle = &BundleConfig{}
+				return nil
+			}), &notAllowed)).To(BeTrue())
+			Expect(errors.As(cf.Update(func(c *Config) error {
+				c.Expansion = ExpansionLegacy
+				return nil
+			}), &notAllowed)).To(BeTrue())
+		})
+
+		It("should validate with the other layers", func() {
+			// org is defined in system layer
+			Expect(cf.Update(func(c *Config) error {
+				return c.SetProfile("other", Profile{Extends: []string{"org"}, Env: Envs{{Name: "VAR", Value: "v"}}})
+			})).To(Succeed())
+
+			var notExisting *ProfileExtendsNotExistingError
+			err := cf.Update(func(c *Config) error {
+				return c.SetProfile("broken", Profile{Extends: []string{"missing"}, Env: Envs{{Name: "VAR", Value: "v"}}})
+			})
+			Expect(errors.As(err, &notExisting)).To(BeTrue())
+		})
+
+		It("should return error if the layer is not existing", func() {
+			cf.SetLayers(system, "")
+			var notExisting *LayerNotExistingError
+			Expect(errors.As(cf.SetWriteLayer(LayerProject), &notExisting)).To(BeTrue())
+			Expect(errors.As(cf.SetWriteLayer("team"), &notExisting)).To(BeTrue())
+		})
+	})
+
+	It("should change the hash if any layer is changed", func() {
+		before, _ := cf.Hash()
+		write(project, "profiles: {}\n")
+		after, _ := cf.Hash()
+		Expect(after).NotTo(Equal(before))
+	})
+})
+
+var _ = Describe("FindProjectConfigFile", func() {
+	It("should find the file in the directory or its parents", func() {
+		dir := GinkgoT().TempDir()
+		nested := filepath.Join(dir, "a", "b")
+		Expect(os.MkdirAll(nested, 0755)).To(Succeed())
+		Expect(FindProjectConfigFile(nested)).To(BeEmpty())
+
+		Expect(os.WriteFile(filepath.Join(dir, ProjectConfigFile), []byte("profiles: {}\n"), 0600)).To(Succeed())
+		Expect(FindProjectConfigFile(nested)).To(Equal(filepath.Join(dir, ProjectConfigFile)))
+	})
+})
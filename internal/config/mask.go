@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// removedLine marks the line that is removed by masking
+const removedLine = "\x00"
+
+// MaskSecrets returns the content of config file that the values of secret env vars are replaced with MaskedValue.
+// only the lines of the values are changed so that the content can be compared with other content line by line.
+// content is returned as it is if it cannot be parsed
+func MaskSecrets(b []byte) []byte {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil || len(doc.Content) == 0 {
+		return b
+	}
+	lines := strings.Split(string(b), "\n")
+	masked := map[int]bool{}
+	secretValues(doc.Content[0], func(key, value *yaml.Node) {
+		line := value.Line - 1
+		if masked[line] || line >= len(lines) || value.Column-1 > len(lines[line]) {
+			return
+		}
+		masked[line] = true
+		lines[line] = lines[line][:value.Column-1] + MaskedValue
+		// continuation lines of multi-line value are more indented than the key
+		for i := line + 1; i < len(lines) && continues(lines[i:], key.Column); i++ {
+			masked[i] = true
+			lines[i] = removedLine
+		}
+	})
+	if len(masked) == 0 {
+		return b
+	}
+	result := make([]string, 0, len(lines))
+	for _, l := range lines {
+		if l != removedLine {
+			result = append(result, l)
+		}
+	}
+	return []byte(strings.Join(result, "\n"))
+}
+
+// continues returns whether the next non-blank line of lines is indented at column or more
+func continues(lines []string, column int) bool {
+	for _, l := range lines {
+		if strings.TrimSpace(l) != "" {
+			return len(l)-len(strings.TrimLeft(l, " ")) >= column
+		}
+	}
+	return false
+}
+
+// secretValues calls fn with key and value node of "value" of the env items that are secret
+func secretValues(n *yaml.Node, fn func(key, value *yaml.Node)) {
+	if n.Kind != yaml.MappingNode {
+		for _, c := range n.Content {
+			secretValues(c, fn)
+		}
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, v := n.Content[i], n.Content[i+1]
+		if k.Value != "env" || v.Kind != yaml.SequenceNode {
+			secretValues(v, fn)
+			continue
+		}
+		for _, item := range v.Content {
+			var e Env
+			if item.Kind != yaml.MappingNode || item.Decode(&e) != nil || !e.IsSecret() {
+				continue
+			}
+			for j := 0; j+1 < len(item.Content); j += 2 {
+				if item.Content[j].Value == "value" && item.Content[j+1].Kind == yaml.ScalarNode {
+					fn(item.Content[j], item.Content[j+1])
+				}
+			}
+		}
+	}
+}
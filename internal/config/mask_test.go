@@ -0,0 +1,54 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskSecrets(t *testing.T) {
+	raw := `profiles:
+  a:
+    env:
+      - name: TOKEN
+        value: "plaintext-token" # comment
+        secret: true
+      - name: KEY
+        value: |
+          line-1
+
+          line-2
+        secret: true
+      - name: ENCRYPTED
+        value: enc:v1:abcd
+      - name: PUBLIC
+        value: public
+
+    b:
+      env:
+        - {name: VAR, value: b}
+`
+	expected := `profiles:
+  a:
+    env:
+      - name: TOKEN
+        value: ********
+        secret: true
+      - name: KEY
+        value: ********
+        secret: true
+      - name: ENCRYPTED
+        value: ********
+      - name: PUBLIC
+        value: public
+
+    b:
+      env:
+        - {name: VAR, value: b}
+`
+	assert.Equal(t, expected, string(MaskSecrets([]byte(raw))))
+
+	// content that has no secret or cannot be parsed is as it is
+	assert.Equal(t, "profiles: {}\n", string(MaskSecrets([]byte("profiles: {}\n"))))
+	assert.Equal(t, "profiles: [\n", string(MaskSecrets([]byte("profiles: [\n"))))
+}
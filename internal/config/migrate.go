@@ -0,0 +1,97 @@
+package config
+
+import "fmt"
+
+// CurrentVersion is the version of config file format. config file without version is version 1
+const CurrentVersion = 2
+
+// Migration upgrades Config from the version From to the next version
+type Migration struct {
+	From        int
+	Description string
+	Migrate     func(cfg *Config) error
+}
+
+// migrations is the registry of the migrations. a migration should be added here when the format of config file is changed
+var migrations = []Migration{
+	{
+		From:        1,
+		Description: "convert init-script of string to list of run",
+		Migrate:     migrateInitScriptToList,
+	},
+}
+
+// ConfigVersionNotSupportedError is an error when the version of config file is newer than envp supports
+type ConfigVersionNotSupportedError struct {
+	version int
+}
+
+// NewConfigVersionNotSupportedError create new ConfigVersionNotSupportedError
+func NewConfigVersionNotSupportedError(version int) *ConfigVersionNotSupportedError {
+	return &ConfigVersionNotSupportedError{
+		version: version,
+	}
+}
+
+// Error is to make ConfigVersionNotSupportedError errors
+func (e *ConfigVersionNotSupportedError) Error() string {
+	return fmt.Sprintf("config file version %d is not supported. the latest supported version is %d. please upgrade envp", e.version, CurrentVersion)
+}
+
+// version returns the version of config file format
+func (c *Config) version() int {
+	if c.Version == 0 {
+		return 1
+	}
+	return c.Version
+}
+
+// Migrate upgrades Config to CurrentVersion step by step
+func (c *Config) Migrate() error {
+	v := c.version()
+	if v > CurrentVersion {
+		return NewConfigVersionNotSupportedError(v)
+	}
+	for ; v < CurrentVersion; v++ {
+		m, err := migrationFrom(v)
+		if err != nil {
+			return err
+		}
+		if err := m.Migrate(c); err != nil {
+			return fmt.Errorf("failed to migrate config from version %d to %d: %w", v, v+1, err)
+		}
+		c.Version = v + 1
+		c.migrations = append(c.migrations, *m)
+	}
+	return nil
+}
+
+// Migrations returns the migrations that are applied to Config since it was read
+func (c *Config) Migrations() []Migration {
+	return c.migrations
+}
+
+// migrationFrom returns the migration from the version
+func migrationFrom(version int) (*Migration, error) {
+	for i := range migrations {
+		if migrations[i].From == version {
+			return &migrations[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no migration from version %d", version)
+}
+
+// migrateInitScriptToList converts init-script of string to list of run. `init-script: echo` becomes `init-script: [{run: echo}]`
+func migrateInitScriptToList(cfg *Config) error {
+	if cfg.Profiles == nil {
+		return nil
+	}
+	cfg.Profiles.Walk(func(name string, p *Profile) {
+		if s, ok := p.InitScript.(string); ok {
+			p.InitScript = []interface{}{
+				map[string]interface{}{"run": s},
+			}
+		}
+	})
+	return nil
+}
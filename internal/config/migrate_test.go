@@ -0,0 +1,41 @@
+package config
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Migrate", func() {
+	When("config has no version", func() {
+		It("should migrate it to the current version step by step", func() {
+			c, err := ParseConfig([]byte("default: a\nprofiles:\n  a:\n    init-script: echo meow\n  b:\n    init-script:\n    - run: echo meow\n"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Migrate()).To(Succeed())
+			Expect(c.Version).To(Equal(CurrentVersion))
+			Expect(c.Migrations()).To(HaveLen(CurrentVersion - 1))
+
+			a, _ := c.Profile("a")
+			Expect(a.InitScript).To(Equal([]interface{}{map[string]interface{}{"run": "echo meow"}}))
+			b, _ := c.Profile("b")
+			Expect(b.InitScripts()).To(Equal([]string{"echo meow"}))
+		})
+	})
+
+	When("config is the current version", func() {
+		It("should not migrate", func() {
+			c, _ := ParseConfig([]byte("version: 2\nprofiles: {}\n"))
+			Expect(c.Migrate()).To(Succeed())
+			Expect(c.Migrations()).To(BeEmpty())
+		})
+	})
+
+	When("config is newer than supported", func() {
+		It("should return error", func() {
+			c, _ := ParseConfig([]byte("version: 99\nprofiles: {}\n"))
+			var notSupported *ConfigVersionNotSupportedError
+			Expect(errors.As(c.Migrate(), &notSupported)).To(BeTrue())
+		})
+	})
+})
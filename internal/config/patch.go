@@ -0,0 +1,511 @@
+package config
+
+import (
+	"bytes"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigNotPatchableError is an error when the change of config cannot be applied to the original content of config file
+// without losing its comments and layout. e.g. the change of anchored node that affects its aliases
+type ConfigNotPatchableError struct{}
+
+// NewConfigNotPatchableError create new ConfigNotPatchableError
+func NewConfigNotPatchableError() *ConfigNotPatchableError {
+	return &ConfigNotPatchableError{}
+}
+
+// Error is to make ConfigNotPatchableError errors
+func (e *ConfigNotPatchableError) Error() string {
+	return "the change cannot be applied without losing comments and layout of config file. edit config file directly"
+}
+
+// lineEdit replaces lines [start, end) of the content with text. it is insertion if start and end are same
+type lineEdit struct {
+	start, end int
+	text       string
+}
+
+// patcher applies changes of nodes to the original content line by line. lines of the nodes that are not changed are kept as they are,
+// so comments, blank lines, anchors and ordering of hand-edited config file are preserved
+type patcher struct {
+	lines   []string
+	indent  int  // indentation of the original content
+	compact bool // whether sequences are not indented from its parent key in the original content
+	edits   []lineEdit
+	failed  bool
+}
+
+// patch applies cfg to the original content of config file by replacing only the lines of the changed nodes.
+// it returns ConfigNotPatchableError if the result does not represent cfg exactly
+func patch(raw []byte, cfg *Config) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || !isBlock(doc.Content[0], yaml.MappingNode) {
+		return nil, NewConfigNotPatchableError()
+	}
+
+	var want yaml.Node
+	if err := want.Encode(cfg); err != nil {
+		return nil, err
+	}
+
+	p := &patcher{
+		lines:  strings.SplitAfter(string(raw), "\n"),
+		indent: 4,
+	}
+	p.detectStyle(doc.Content[0])
+	p.mapping(doc.Content[0], &want)
+	if p.failed {
+		return nil, NewConfigNotPatchableError()
+	}
+	b := p.apply()
+
+	// ensure the patched content is exactly same as cfg. e.g. change of anchored node affects its aliases
+	patched, err := ParseConfig(b)
+	if err != nil {
+		return nil, NewConfigNotPatchableError()
+	}
+	expected, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := yaml.Marshal(patched)
+	if err != nil || !bytes.Equal(expected, actual) {
+		return nil, NewConfigNotPatchableError()
+	}
+	return b, nil
+}
+
+// detectStyle detects indentation and sequence style from the first nested mapping and sequence of node
+func (p *patcher) detectStyle(node *yaml.Node) {
+	indentFound, seqFound := false, false
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if indentFound && seqFound {
+			return
+		}
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				switch {
+				case !indentFound && isBlock(v, yaml.MappingNode) && v.Content[0].Column > k.Column:
+					p.indent = v.Content[0].Column - k.Column
+					indentFound = true
+				case !seqFound && isBlock(v, yaml.SequenceNode):
+					if col, ok := p.dashColumn(v.Content[0]); ok {
+						p.compact = col == k.Column-1
+						seqFound = true
+					}
+				}
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(node)
+}
+
+// mapping applies the changes of mapping node to old mapping node
+func (p *patcher) mapping(old, new *yaml.Node) {
+	values := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(new.Content); i += 2 {
+		values[new.Content[i].Value] = new.Content[i+1]
+	}
+
+	keys := map[string]*yaml.Node{}
+	for i := 0; i+1 < len(old.Content); i += 2 {
+		k, v := old.Content[i], old.Content[i+1]
+		keys[k.Value] = k
+		// merge key is kept as it is. overridden values are added as keys of the mapping
+		if k.Value == "<<" {
+			continue
+		}
+		nv, ok := values[k.Value]
+		switch {
+		case !ok:
+			p.remove(old, i)
+		case equalNode(v, nv):
+			// not changed
+		case isBlock(v, yaml.MappingNode) && isBlock(nv, yaml.MappingNode):
+			p.recurse(func() { p.mapping(v, nv) }, func() { p.replace(old, i, nv) })
+		case isBlock(v, yaml.SequenceNode) && isBlock(nv, yaml.SequenceNode):
+			p.recurse(func() { p.sequence(v, nv) }, func() { p.replace(old, i, nv) })
+		default:
+			// empty mapping or sequence is replaced as a whole since there is no line to keep. e.g. group that has no profile
+			p.replace(old, i, nv)
+		}
+	}
+
+	// values that are merged with merge key are not added
+	merged := map[string]interface{}{}
+	if _, ok := keys["<<"]; ok {
+		old.Decode(&merged)
+	}
+
+	// new keys are added before the next key that is existing in old mapping to follow the order of new mapping.
+	// or after the last entry of the mapping if there is no next key
+	added := []*yaml.Node{}
+	for i := 0; i+1 < len(new.Content); i += 2 {
+		k, v := new.Content[i], new.Content[i+1]
+		if next, ok := keys[k.Value]; ok {
+			if len(added) > 0 && p.insertBefore(next, added) {
+				added = []*yaml.Node{}
+			}
+			continue
+		}
+		if mv, ok := merged[k.Value]; ok && equalValue(mv, v) {
+			continue
+		}
+		added = append(added, k, v)
+	}
+	if len(added) > 0 {
+		k := old.Content[0]
+		end := p.entryEnd(old, len(old.Content)-2)
+		p.edit(end, end, p.encode(&yaml.Node{Kind: yaml.MappingNode, Content: added}, strings.Repeat(" ", k.Column-1), k.Column-1))
+	}
+}
+
+// recurse applies the changes of nested node with apply. the edits of apply are discarded and the node is replaced as a whole
+// with replace if the changes cannot be reached by recursing. e.g. removal of the key that shares the line with its parent
+func (p *patcher) recurse(apply, replace func()) {
+	if p.failed {
+		return
+	}
+	n := len(p.edits)
+	apply()
+	if p.failed {
+		p.edits = p.edits[:n]
+		p.failed = false
+		replace()
+	}
+}
+
+// insertBefore inserts the keys and values before the key. it returns false if the key shares the line with its parent
+func (p *patcher) insertBefore(key *yaml.Node, content []*yaml.Node) bool {
+	start := key.Line - 1
+	if strings.TrimSpace(p.lines[start][:key.Column-1]) != "" {
+		return false
+	}
+	p.edit(start, start, p.encode(&yaml.Node{Kind: yaml.MappingNode, Content: content}, strings.Repeat(" ", key.Column-1), key.Column-1))
+	return true
+}
+
+// sequence applies the changes of sequence node to old sequence node item by item
+func (p *patcher) sequence(old, new *yaml.Node) {
+	n := min(len(old.Content), len(new.Content))
+	for i := 0; i < n; i++ {
+		o, nv := old.Content[i], new.Content[i]
+		switch {
+		case equalNode(o, nv):
+			// not changed
+		case isBlock(o, yaml.MappingNode) && isBlock(nv, yaml.MappingNode):
+			p.recurse(func() { p.mapping(o, nv) }, func() {
+				p.replaceItem(old, i, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{nv}})
+			})
+		default:
+			p.replaceItem(old, i, &yaml.Node{Kind: yaml.SequenceNode, Content: []*yaml.Node{nv}})
+		}
+	}
+	for i := n; i < len(old.Content); i++ {
+		p.replaceItem(old, i, nil)
+	}
+	if len(new.Content) > n {
+		col, ok := p.dashColumn(old.Content[0])
+		if !ok {
+			p.failed = true
+			return
+		}
+		end := p.itemEnd(old, len(old.Content)-1)
+		p.edit(end, end, p.encode(&yaml.Node{Kind: yaml.SequenceNode, Content: new.Content[n:]}, strings.Repeat(" ", col), col))
+	}
+}
+
+// replace replaces the value of i-th key of mapping with value
+func (p *patcher) replace(mapping *yaml.Node, i int, value *yaml.Node) {
+	k, v := mapping.Content[i], mapping.Content[i+1]
+	if v.Kind == yaml.ScalarNode && value.Kind == yaml.ScalarNode {
+		// keep comment and quoting style of scalar
+		value.LineComment = v.LineComment
+		if v.Tag == value.Tag && v.Style&(yaml.DoubleQuotedStyle|yaml.SingleQuotedStyle) != 0 {
+			value.Style = v.Style
+		}
+	}
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: k.Tag, Style: k.Style, Value: k.Value, LineComment: k.LineComment}
+	start := k.Line - 1
+	p.edit(start, p.entryEnd(mapping, i), p.encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}}, p.lines[start][:k.Column-1], k.Column-1))
+}
+
+// remove removes i-th key of mapping with its head comment
+func (p *patcher) remove(mapping *yaml.Node, i int) {
+	k := mapping.Content[i]
+	start := k.Line - 1
+	if strings.TrimSpace(p.lines[start][:k.Column-1]) != "" {
+		// key shares the line with its parent. e.g. first key of sequence item
+		p.failed = true
+		return
+	}
+	for start > 0 && isComment(p.lines[start-1]) && indentOf(p.lines[start-1]) == k.Column-1 {
+		start--
+	}
+	// remove blank lines that separate the entry from next one. or from previous one if it is the last entry
+	end := p.entryEnd(mapping, i)
+	if i+2 < len(mapping.Content) {
+		for end < len(p.lines) && isBlank(p.lines[end]) {
+			end++
+		}
+	} else {
+		for start > 0 && isBlank(p.lines[start-1]) {
+			start--
+		}
+	}
+	p.edit(start, end, "")
+}
+
+// replaceItem replaces i-th item of sequence with items. it is removed if items is nil
+func (p *patcher) replaceItem(seq *yaml.Node, i int, items *yaml.Node) {
+	item := seq.Content[i]
+	col, ok := p.dashColumn(item)
+	if !ok || strings.TrimSpace(p.lines[item.Line-1][:col]) != "" {
+		p.failed = true
+		return
+	}
+	text := ""
+	if items != nil {
+		text = p.encode(items, strings.Repeat(" ", col), col)
+	}
+	p.edit(item.Line-1, p.itemEnd(seq, i), text)
+}
+
+// entryEnd returns the line index right after the i-th key and value of mapping
+func (p *patcher) entryEnd(mapping *yaml.Node, i int) int {
+	k, v := mapping.Content[i], mapping.Content[i+1]
+	return p.extendEnd(max(k.Line, lastLine(v)), k.Column-1)
+}
+
+// itemEnd returns the line index right after the i-th item of sequence
+func (p *patcher) itemEnd(seq *yaml.Node, i int) int {
+	col, _ := p.dashColumn(seq.Content[i])
+	return p.extendEnd(lastLine(seq.Content[i]), col)
+}
+
+// extendEnd extends the end of node over the continuation lines that are indented deeper than col.
+// e.g. multi-line scalar and closing bracket of flow style. trailing blank and comment lines are not included
+func (p *patcher) extendEnd(end, col int) int {
+	for i := end; i < len(p.lines); i++ {
+		line := p.lines[i]
+		if isBlank(line) || isComment(line) {
+			continue
+		}
+		if indentOf(line) <= col {
+			break
+		}
+		end = i + 1
+	}
+	return end
+}
+
+// dashColumn returns 0 based column of the dash of sequence item
+func (p *patcher) dashColumn(item *yaml.Node) (int, bool) {
+	if item.Line < 1 || item.Line > len(p.lines) {
+		return 0, false
+	}
+	line := p.lines[item.Line-1]
+	if item.Column-1 > len(line) {
+		return 0, false
+	}
+	i := strings.LastIndex(line[:item.Column-1], "-")
+	return i, i >= 0
+}
+
+// encode encodes node with the style of original content. first line is prefixed with prefix and others are indented with indent spaces
+func (p *patcher) encode(node *yaml.Node, prefix string, indent int) string {
+	b, err := encodeNode(node, p.indent, p.compact)
+	if err != nil {
+		p.failed = true
+		return ""
+	}
+
+	var sb strings.Builder
+	for i, line := range strings.SplitAfter(strings.TrimSuffix(string(b), "\n"), "\n") {
+		if i == 0 {
+			sb.WriteString(prefix)
+		} else if strings.TrimSpace(line) != "" {
+			sb.WriteString(strings.Repeat(" ", indent))
+		}
+		sb.WriteString(line)
+	}
+	sb.WriteString("\n")
+	return sb.String()
+}
+
+// encodeNode encodes node with indent. block sequences are not indented from their parent key if compact is true
+func encodeNode(node *yaml.Node, indent int, compact bool) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(indent)
+	if err := enc.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	if !compact {
+		return buf.Bytes(), nil
+	}
+	return compactSequences(buf.Bytes(), indent)
+}
+
+// compactSequences outdents the lines of block sequences that are values of mapping by indent,
+// since encoder always indents the sequences from their parent key
+func compactSequences(b []byte, indent int) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		return nil, err
+	}
+	lines := strings.SplitAfter(string(b), "\n")
+	shift := make([]int, len(lines))
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				k, v := n.Content[i], n.Content[i+1]
+				if !isBlock(v, yaml.SequenceNode) || len(v.Content) == 0 {
+					continue
+				}
+				// the sequence continues over the lines that are indented deeper than its key
+				for l := v.Line - 1; l < len(lines) && (l == v.Line-1 || isBlank(lines[l]) || indentOf(lines[l]) > k.Column-1); l++ {
+					shift[l] += indent
+				}
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(&doc)
+
+	var sb strings.Builder
+	for i, line := range lines {
+		sb.WriteString(line[min(shift[i], indentOf(line)):])
+	}
+	return []byte(sb.String()), nil
+}
+
+// edit adds the edit of lines
+func (p *patcher) edit(start, end int, text string) {
+	p.edits = append(p.edits, lineEdit{start: start, end: end, text: text})
+}
+
+// apply applies the edits to the lines and returns the content
+func (p *patcher) apply() []byte {
+	lines := append([]string{}, p.lines...)
+	// ensure the last line ends with new line before something is inserted after it
+	if n := len(lines); n > 0 && lines[n-1] != "" && !strings.HasSuffix(lines[n-1], "\n") {
+		lines[n-1] += "\n"
+	}
+	// apply from the bottom so that line index of other edits are not shifted.
+	// insertion at the start of replaced lines is applied after the replacement to put it before the replaced lines
+	edits := append([]lineEdit{}, p.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	for _, e := range edits {
+		lines = append(lines[:e.start], append([]string{e.text}, lines[e.end:]...)...)
+	}
+	return []byte(strings.Join(lines, ""))
+}
+
+// equalNode checks if the values of nodes are same regardless of style, comments, anchors and empty values
+func equalNode(a, b *yaml.Node) bool {
+	var va interface{}
+	if err := a.Decode(&va); err != nil {
+		return false
+	}
+	return equalValue(va, b)
+}
+
+// equalValue checks if the decoded value is same as the value of node regardless of empty values
+func equalValue(v interface{}, n *yaml.Node) bool {
+	var nv interface{}
+	if err := n.Decode(&nv); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(compact(v), compact(nv))
+}
+
+// compact removes empty values from the maps. they are omitted when Config is encoded
+func compact(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		m := map[string]interface{}{}
+		for k, val := range t {
+			val = compact(val)
+			if isEmpty(val) {
+				continue
+			}
+			m[k] = val
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, val := range t {
+			s[i] = compact(val)
+		}
+		return s
+	}
+	return v
+}
+
+// isEmpty checks if v is empty value
+func isEmpty(v interface{}) bool {
+	switch t := v.(type) {
+	case nil:
+		return true
+	case string:
+		return t == ""
+	case map[string]interface{}:
+		return len(t) == 0
+	case []interface{}:
+		return len(t) == 0
+	}
+	return false
+}
+
+// isBlock checks if node is non-empty block style node of kind
+func isBlock(n *yaml.Node, kind yaml.Kind) bool {
+	return n.Kind == kind && n.Style&yaml.FlowStyle == 0 && len(n.Content) > 0
+}
+
+// lastLine returns the last line number of the node and its children
+func lastLine(n *yaml.Node) int {
+	line := n.Line
+	for _, c := range n.Content {
+		line = max(line, lastLine(c))
+	}
+	return line
+}
+
+// isBlank checks if the line is blank
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// isComment checks if the line is comment only
+func isComment(line string) bool {
+	return strings.HasPrefix(strings.TrimSpace(line), "#")
+}
+
+// indentOf returns the number of leading spaces of the line
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var updateGolden = flag.Bool("update", false, "update golden files of config round-trip and schema")
+
+var _ = Describe("Round-trip of config file", func() {
+	raw, _ := os.ReadFile("testdata/roundtrip.yaml")
+
+	// write applies fn to the config of testdata/roundtrip.yaml and returns the content to be written
+	write := func(fn func(c *Config)) string {
+		c, err := ParseConfig(raw)
+		Expect(err).NotTo(HaveOccurred())
+		fn(c)
+		b, err := Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+		return string(b)
+	}
+
+	golden := func(name, actual string) {
+		file := filepath.Join("testdata", "golden", name+".yaml")
+		if *updateGolden {
+			Expect(os.WriteFile(file, []byte(actual), 0644)).To(Succeed())
+		}
+		expected, err := os.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(actual).To(Equal(string(expected)))
+	}
+
+	It("should keep the content as it is if nothing is changed", func() {
+		Expect(write(func(c *Config) {})).To(Equal(string(raw)))
+	})
+
+	It("should change only default when default profile is changed", func() {
+		golden("use", write(func(c *Config) {
+			c.SetDefault("lab.cluster1")
+		}))
+	})
+
+	It("should change only the env of edited profile", func() {
+		golden("edit", write(func(c *Config) {
+			p, _ := c.Profile("docker")
+			p.Desc = "remote docker"
+			p.Env = append(p.Env, &Env{Name: "DOCKER_TLS_VERIFY", Value: "1"})
+			p, _ = c.Profile("lab.cluster2")
+			p.Env[2].Value = "/Users/meow/.kube/config"
+		}))
+	})
+
+	It("should add the profile at the end of its parent", func() {
+		golden("add", write(func(c *Config) {
+			c.SetProfile("org.nprod.vpn.vpn3", Profile{
+				Desc: "org.nprod.vpn.vpn3",
+				Env:  Envs{{Name: "HTTPS_PROXY", Value: "http://192.168.2.12:3128"}},
+			})
+		}))
+	})
+
+	It("should remove only the lines of deleted profile", func() {
+		golden("delete", write(func(c *Config) {
+			c.DeleteProfile("lab")
+			c.DeleteProfile("parent-has-env")
+		}))
+	})
+
+	It("should keep the group when its last nested profile is deleted", func() {
+		golden("delete-nested", write(func(c *Config) {
+			c.DeleteProfile("org.nprod.vpn.vpn1")
+			c.DeleteProfile("org.nprod.vpn.vpn2")
+		}))
+	})
+
+	It("should add version on top and change only the migrated profiles", func() {
+		golden("migrate", write(func(c *Config) {
+			Expect(c.Migrate()).To(Succeed())
+		}))
+	})
+
+	It("should keep comments, blank lines and anchors of hand-edited config", func() {
+		raw := []byte(`# my config
+default: base
+
+profiles:
+  # shared one
+  base: &base
+    desc: "base"   # quoted
+    env:
+      - name: A
+        value: a
+
+  other:
+    <<: *base
+    desc: other
+`)
+		c, err := ParseConfig(raw)
+		Expect(err).NotTo(HaveOccurred())
+		c.SetDefault("other")
+		p, _ := c.Profile("other")
+		p.Desc = "changed"
+		b, err := Marshal(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b)).To(Equal(`# my config
+default: other
+
+profiles:
+  # shared one
+  base: &base
+    desc: "base"   # quoted
+    env:
+      - name: A
+        value: a
+
+  other:
+    <<: *base
+    desc: changed
+`))
+	})
+
+	When("the change affects the aliases", func() {
+		It("should return error instead of dropping comments and layout", func() {
+			c, err := ParseConfig([]byte("default: a\nprofiles:\n  a: &a\n    desc: a\n  b: *a\n"))
+			Expect(err).NotTo(HaveOccurred())
+			p, _ := c.Profile("a")
+			p.Desc = "changed"
+			_, err = Marshal(c)
+			var notPatchableErr *ConfigNotPatchableError
+			Expect(errors.As(err, &notPatchableErr)).To(BeTrue())
+		})
+	})
+})
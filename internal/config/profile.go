@@ -18,6 +18,11 @@ type Profile struct {
 	Desc       string      `mapstructure:"desc" yaml:"desc,omitempty"`
 	Env        Envs        `mapstructure:"env" yaml:"env,omitempty"`
 	InitScript interface{} `mapstructure:"init-script" yaml:"init-script,omitempty"`
+	// Inherit controls whether the profile inherits env and init-script from its parent groups.
+	// it is inherited by default. set it false to stop the inheritance at this profile(group).
+	Inherit *bool `mapstructure:"inherit" yaml:"inherit,omitempty"`
+	// Extends is list of other profiles to be composed into the profile. later one wins.
+	Extends []string `mapstructure:"extends" yaml:"extends,omitempty"`
 }
 
 // NewProfile creates the Profile
@@ -64,6 +69,42 @@ func NewProfileNameInputEmptyError() *ProfileNameInputEmptyError {
 	return &ProfileNameInputEmptyError{}
 }
 
+// ProfileExtendsCycleError is an error when profiles are extending each other
+type ProfileExtendsCycleError struct {
+	profiles []string
+}
+
+// NewProfileExtendsCycleError create new ProfileExtendsCycleError. profiles is the path of the cycle
+func NewProfileExtendsCycleError(profiles []string) *ProfileExtendsCycleError {
+	return &ProfileExtendsCycleError{
+		profiles: profiles,
+	}
+}
+
+// Error is to make ProfileExtendsCycleError errors
+func (e *ProfileExtendsCycleError) Error() string {
+	return fmt.Sprintf("profile has cycle in extends: %s", strings.Join(e.profiles, " -> "))
+}
+
+// ProfileExtendsNotExistingError is an error when profile in the extends is not existing
+type ProfileExtendsNotExistingError struct {
+	profile string
+	extends string
+}
+
+// NewProfileExtendsNotExistingError create new ProfileExtendsNotExistingError
+func NewProfileExtendsNotExistingError(profile, extends string) *ProfileExtendsNotExistingError {
+	return &ProfileExtendsNotExistingError{
+		profile: profile,
+		extends: extends,
+	}
+}
+
+// Error is to make ProfileExtendsNotExistingError errors
+func (e *ProfileExtendsNotExistingError) Error() string {
+	return fmt.Sprintf("profile %s extends %s that is not existing", e.profile, e.extends)
+}
+
 // SetProfile sets profile into the Profiles
 // key is dot "." delimited or plain string without no space.
 // if it is dot delimited, considering it as nested profile
@@ -141,6 +182,33 @@ func (p *Profiles) ProfileNames() []string {
 	return profileList
 }
 
+// Walk calls fn for all the profiles including groups in sorted order of name.
+// nested profile's name will be formatted as "my-grand-parent.my-parent.me"
+func (p *Profiles) Walk(fn func(name string, profile *Profile)) {
+	walkProfiles("", *p, fn)
+}
+
+// walkProfiles do DFS of profiles in sorted order of key
+func walkProfiles(key string, profiles Profiles, fn func(name string, profile *Profile)) {
+	keys := make([]string, 0, len(profiles))
+	for k := range profiles {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		// profile of null value such as `name:` has nothing to walk
+		if profiles[k] == nil {
+			continue
+		}
+		name := k
+		if key != "" {
+			name = fmt.Sprint(key, ".", k)
+		}
+		fn(name, profiles[k])
+		walkProfiles(name, profiles[k].Profiles, fn)
+	}
+}
+
 // DeleteProfile delete profile
 func (p *Profiles) DeleteProfile(key string) error {
 	if key == "" {
@@ -211,7 +279,7 @@ func findProfileByDotNotationKey(keys []string, profiles *Profiles) *Profile {
 	current := *profiles
 	var profile *Profile
 	for _, k := range keys {
-		if p, ok := current[k]; ok {
+		if p, ok := current[k]; ok && p != nil {
 			current = p.Profiles
 			profile = p
 		} else {
@@ -221,10 +289,18 @@ func findProfileByDotNotationKey(keys []string, profiles *Profiles) *Profile {
 	return profile
 }
 
+// inherits returns whether the profile inherits env and init-script from its parent groups
+func (p *Profile) inherits() bool {
+	return p.Inherit == nil || *p.Inherit
+}
+
 // list all the profiles in dot "." format. e.g. my-group.my-subgroup.my-profile
 // Do DFS to build viper keys for profiles
 func listProfileKeys(key string, profiles Profiles, arr *[]string) *[]string {
 	for k, v := range profiles {
+		if v == nil {
+			continue
+		}
 		var s string
 		if key == "" {
 			s = k
@@ -100,6 +100,21 @@ func TestProfileNames(t *testing.T) {
 	}
 }
 
+// profile of null value should be skipped instead of panic
+func TestProfilesWithNullProfile(t *testing.T) {
+	cfg, err := config.ParseConfig([]byte("profiles:\n  a:\n    env:\n    - name: A\n      value: a\n  nullprof:\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"a"}, cfg.ProfileNames())
+	walked := []string{}
+	cfg.Profiles.Walk(func(name string, p *config.Profile) {
+		walked = append(walked, name)
+	})
+	assert.Equal(t, []string{"a"}, walked)
+	_, err = cfg.Profile("nullprof")
+	assert.Error(t, err)
+}
+
 // testing FindParentProfile
 func TestFindParentProfile(t *testing.T) {
 	profiles := testDataProfiles()
@@ -319,3 +334,141 @@ func TestProfileInitScript(t *testing.T) {
 		assert.Len(t, p.InitScripts(), expect, fmt.Sprintf("should be %v init-script", expect))
 	})
 }
+
+func TestResolveProfile(t *testing.T) {
+
+	data := `
+profiles:
+  lab:
+    env:
+    - name: NO_PROXY
+      value: localhost,127.0.0.1
+    - name: HTTPS_PROXY
+      value: http://lab:3128
+    init-script: echo lab
+    cluster1:
+      env:
+      - name: HTTPS_PROXY
+        value: http://cluster1:3128
+      - name: KUBECONFIG
+        value: /kube/cluster1
+      init-script:
+      - run: echo cluster1
+    isolated:
+      inherit: false
+      env:
+      - name: KUBECONFIG
+        value: /kube/isolated
+      child:
+        env:
+        - name: VAR
+          value: VAL
+`
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("child should inherit env and init-script of parent", func(t *testing.T) {
+		p, err := cfg.Profiles.ResolveProfile("lab.cluster1")
+		assert.NoError(t, err)
+		assert.Equal(t, "NO_PROXY=localhost,127.0.0.1,HTTPS_PROXY=http://cluster1:3128,KUBECONFIG=/kube/cluster1", p.Env.String())
+		assert.Equal(t, []string{"echo lab", "echo cluster1"}, p.InitScripts())
+	})
+
+	t.Run("inheritance should stop at the profile that has inherit false", func(t *testing.T) {
+		p, err := cfg.Profiles.ResolveProfile("lab.isolated")
+		assert.NoError(t, err)
+		assert.Equal(t, "KUBECONFIG=/kube/isolated", p.Env.String())
+		assert.Empty(t, p.InitScripts())
+
+		p, err = cfg.Profiles.ResolveProfile("lab.isolated.child")
+		assert.NoError(t, err)
+		assert.Equal(t, "KUBECONFIG=/kube/isolated,VAR=VAL", p.Env.String())
+	})
+
+	t.Run("resolved profile should not change original profile", func(t *testing.T) {
+		p, _ := cfg.Profiles.ResolveProfile("lab.cluster1")
+		p.Env[0].Value = "changed"
+		o, _ := cfg.Profiles.FindProfile("lab")
+		assert.Equal(t, "localhost,127.0.0.1", o.Env[0].Value)
+		o, _ = cfg.Profiles.FindProfile("lab.cluster1")
+		assert.Len(t, o.Env, 2)
+	})
+
+	t.Run("resolve non-existing profile", func(t *testing.T) {
+		_, err := cfg.Profiles.ResolveProfile("lab.not-existing")
+		assert.Error(t, err)
+		_, err = cfg.Profiles.ResolveProfile("")
+		assert.Error(t, err)
+	})
+}
+
+func TestResolveProfileExtends(t *testing.T) {
+
+	data := `
+profiles:
+  corp-proxy:
+    env:
+    - name: HTTPS_PROXY
+      value: http://corp:3128
+    - name: NO_PROXY
+      value: localhost
+    init-script: echo corp-proxy
+  docker-remote:
+    env:
+    - name: DOCKER_HOST
+      value: ssh://docker
+    - name: NO_PROXY
+      value: localhost,docker
+  k8s-lab:
+    extends: [corp-proxy, docker-remote]
+    env:
+    - name: KUBECONFIG
+      value: /kube/lab
+  lab:
+    init-script: echo lab
+    cluster1:
+      extends: [corp-proxy]
+      env:
+      - name: HTTPS_PROXY
+        value: http://cluster1:3128
+  cycle-a:
+    extends: [cycle-b]
+  cycle-b:
+    extends: [cycle-a]
+  missing:
+    extends: [not-existing]
+`
+	var cfg config.Config
+	if err := yaml.Unmarshal([]byte(data), &cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("extends should be merged in order and own env should win", func(t *testing.T) {
+		p, err := cfg.Profiles.ResolveProfile("k8s-lab")
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTPS_PROXY=http://corp:3128,NO_PROXY=localhost,docker,DOCKER_HOST=ssh://docker,KUBECONFIG=/kube/lab", p.Env.String())
+		assert.Equal(t, []string{"echo corp-proxy"}, p.InitScripts())
+	})
+
+	t.Run("extends should be merged after parent groups", func(t *testing.T) {
+		p, err := cfg.Profiles.ResolveProfile("lab.cluster1")
+		assert.NoError(t, err)
+		assert.Equal(t, "HTTPS_PROXY=http://cluster1:3128,NO_PROXY=localhost", p.Env.String())
+		assert.Equal(t, []string{"echo lab", "echo corp-proxy"}, p.InitScripts())
+	})
+
+	t.Run("cycle should be error", func(t *testing.T) {
+		_, err := cfg.Profiles.ResolveProfile("cycle-a")
+		var cycleErr *config.ProfileExtendsCycleError
+		assert.ErrorAs(t, err, &cycleErr)
+		assert.Contains(t, err.Error(), "cycle-a -> cycle-b -> cycle-a")
+	})
+
+	t.Run("missing reference should be error", func(t *testing.T) {
+		_, err := cfg.Profiles.ResolveProfile("missing")
+		var notExistingErr *config.ProfileExtendsNotExistingError
+		assert.ErrorAs(t, err, &notExistingErr)
+	})
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"strings"
+)
+
+// ResolveProfile finds profile from dot notation of profile name and returns merged view of it.
+// profiles are merged in following order, and the later one wins.
+//   - parent groups along the dot path, unless the profile has `inherit: false`
+//   - profiles in the `extends` in order
+//   - the profile itself
+//
+// parent groups and extended profiles are resolved in the same way recursively.
+// returned Profile is a copy. changes on it will not be reflected to the Profiles.
+func (p *Profiles) ResolveProfile(key string) (*Profile, error) {
+	profile, err := p.FindProfile(key)
+	if err != nil {
+		return nil, err
+	}
+
+	r := newProfileResolver(p)
+	if err := r.resolve(key); err != nil {
+		return nil, err
+	}
+
+	resolved := &Profile{
+		Profiles: profile.Profiles,
+		Desc:     profile.Desc,
+		Inherit:  profile.Inherit,
+		Extends:  profile.Extends,
+	}
+	var initScripts []interface{}
+	for _, l := range r.layers {
+		resolved.Env = mergeEnvs(resolved.Env, l.Env)
+		for _, s := range l.InitScripts() {
+			initScripts = append(initScripts, map[string]interface{}{"run": s})
+		}
+	}
+	if len(initScripts) > 0 {
+		resolved.InitScript = initScripts
+	}
+	return resolved, nil
+}
+
+// profileResolver linearizes the profiles to be merged for a profile
+type profileResolver struct {
+	profiles *Profiles
+	layers   []*Profile      // profiles to merge in order
+	names    []string        // names of the profiles in layers
+	path     []string        // profiles that are being resolved. it is used to report the cycle
+	visiting map[string]bool // profiles that are being resolved
+	visited  map[string]bool // profiles that are added into layers already
+}
+
+// newProfileResolver create new profileResolver of profiles
+func newProfileResolver(profiles *Profiles) *profileResolver {
+	return &profileResolver{
+		profiles: profiles,
+		visiting: map[string]bool{},
+		visited:  map[string]bool{},
+	}
+}
+
+// resolve adds layers of profile key. profile that is added already will be skipped so that it is merged only once
+func (r *profileResolver) resolve(key string) error {
+	if r.visited[key] {
+		return nil
+	}
+	if r.visiting[key] {
+		return NewProfileExtendsCycleError(append(r.path, key))
+	}
+	r.visiting[key] = true
+	r.path = append(r.path, key)
+
+	profile, err := r.profiles.FindProfile(key)
+	if err != nil {
+		return err
+	}
+
+	// parent groups
+	if keys := strings.Split(key, "."); len(keys) > 1 && profile.inherits() {
+		if err := r.resolve(strings.Join(keys[:len(keys)-1], ".")); err != nil {
+			return err
+		}
+	}
+
+	// extended profiles
+	for _, e := range profile.Extends {
+		if _, err := r.profiles.FindProfile(e); err != nil {
+			return NewProfileExtendsNotExistingError(key, e)
+		}
+		if err := r.resolve(e); err != nil {
+			return err
+		}
+	}
+
+	r.path = r.path[:len(r.path)-1]
+	r.visiting[key] = false
+	r.visited[key] = true
+	r.layers = append(r.layers, profile)
+	r.names = append(r.names, key)
+	return nil
+}
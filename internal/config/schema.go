@@ -0,0 +1,366 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaID is $id of the JSON Schema of config file
+const SchemaID = "https://github.com/sunggun-yu/envp/config.schema.json"
+
+// JSONSchema is the subset of JSON Schema draft 2020-12 that is needed to describe config file
+type JSONSchema struct {
+	never                bool                   // false schema that nothing is valid against
+	pattern              *regexp.Regexp         // compiled Pattern. it is compiled once when the schema is generated
+	Schema               string                 `json:"$schema,omitempty"`
+	ID                   string                 `json:"$id,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Enum                 []string               `json:"enum,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Minimum              *int                   `json:"minimum,omitempty"`
+	OneOf                []*JSONSchema          `json:"oneOf,omitempty"`
+	Defs                 map[string]*JSONSchema `json:"$defs,omitempty"`
+}
+
+// MarshalJSON marshals false schema as false
+func (s *JSONSchema) MarshalJSON() ([]byte, error) {
+	if s.never {
+		return []byte("false"), nil
+	}
+	type schema JSONSchema
+	return json.Marshal((*schema)(s))
+}
+
+// envNamePattern is POSIX name of env var
+const envNamePattern = `^[A-Za-z_][A-Za-z0-9_]*$`
+
+// durationPattern is format of time.Duration. e.g. 1m30s
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// schemaFields has the constraints of the fields that can't be derived from their types. key is Type.Field
+var schemaFields = map[string]func(s *JSONSchema){
+	"Config.Version": func(s *JSONSchema) {
+		s.Minimum = new(int)
+		*s.Minimum = 1
+	},
+	"Config.Expansion": func(s *JSONSchema) {
+		s.Enum = []string{ExpansionNative, ExpansionLegacy}
+	},
+	"Env.Name": func(s *JSONSchema) {
+		s.Pattern = envNamePattern
+	},
+	"Env.Op": func(s *JSONSchema) {
+		s.Enum = []string{EnvOpSet, EnvOpPrepend, EnvOpAppend, EnvOpUnset}
+	},
+	"Profile.InitScript": func(s *JSONSchema) {
+		*s = JSONSchema{
+			Description: "script that runs before starting the profile. string is the format of version 1",
+			OneOf: []*JSONSchema{
+				{Type: "string"},
+				{
+					Type: "array",
+					Items: &JSONSchema{
+						Type: "object",
+						Properties: map[string]*JSONSchema{
+							"run": {Type: "string"},
+						},
+						AdditionalProperties: &JSONSchema{never: true},
+						Required:             []string{"run"},
+					},
+				},
+			},
+		}
+	},
+}
+
+// schemaRequired has the required keys of the types
+var schemaRequired = map[string][]string{
+	"Env":        {"name"},
+	"TrustedKey": {"name", "public-key"},
+}
+
+// Schema returns JSON Schema of config file that is generated from the Config, Profile and Env types.
+// child profiles are inline keys of profile that refer the profile schema recursively
+func Schema() *JSONSchema {
+	g := &schemaGenerator{defs: map[string]*JSONSchema{}}
+	s := g.object(reflect.TypeOf(Config{}))
+	s.Schema = "https://json-schema.org/draft/2020-12/schema"
+	s.ID = SchemaID
+	s.Title = "envp config"
+	s.Defs = g.defs
+	s.compile()
+	return s
+}
+
+// compile compiles Pattern of the schema and its sub schemas
+func (s *JSONSchema) compile() {
+	if s == nil {
+		return
+	}
+	if s.Pattern != "" && s.pattern == nil {
+		s.pattern = regexp.MustCompile(s.Pattern)
+	}
+	for _, p := range s.Properties {
+		p.compile()
+	}
+	for _, d := range s.Defs {
+		d.compile()
+	}
+	for _, o := range s.OneOf {
+		o.compile()
+	}
+	s.AdditionalProperties.compile()
+	s.Items.compile()
+}
+
+// schemaGenerator generates schema of the types. struct types except for Config are defined in defs
+type schemaGenerator struct {
+	defs map[string]*JSONSchema
+}
+
+// schema returns schema of type t
+func (g *schemaGenerator) schema(t reflect.Type) *JSONSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == reflect.TypeOf(time.Duration(0)):
+		return &JSONSchema{Type: "string", Pattern: durationPattern}
+	case t == reflect.TypeOf(Profiles{}):
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schema(reflect.TypeOf(Profile{}))}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// placeholder to stop recursion of profile
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.object(t)
+		}
+		return &JSONSchema{Ref: "#/$defs/" + t.Name()}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Slice:
+		return &JSONSchema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &JSONSchema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	}
+	// interface accepts anything
+	return &JSONSchema{}
+}
+
+// object returns schema of struct type t with the properties of yaml keys. inline map is the additional properties
+func (g *schemaGenerator) object(t reflect.Type) *JSONSchema {
+	s := &JSONSchema{
+		Type:                 "object",
+		Properties:           map[string]*JSONSchema{},
+		AdditionalProperties: &JSONSchema{never: true},
+		Required:             schemaRequired[t.Name()],
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		fs := g.schema(f.Type)
+		if strings.Contains(opts, "inline") {
+			s.AdditionalProperties = fs.AdditionalProperties
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		if fn, ok := schemaFields[t.Name()+"."+f.Name]; ok {
+			fn(fs)
+		}
+		s.Properties[name] = fs
+	}
+	return s
+}
+
+// schemaError is a violation of the schema at the node. parent and key are set if the key is not allowed in the parent mapping
+type schemaError struct {
+	node    *yaml.Node
+	message string
+	parent  *yaml.Node
+	key     string
+}
+
+// validateNode validates the node against the schema. path is the location of the node in the messages
+func (s *JSONSchema) validateNode(root *JSONSchema, n *yaml.Node, path string) []schemaError {
+	n = resolveAlias(n)
+	if s.never {
+		return []schemaError{{node: n, message: fmt.Sprintf("%s is not allowed", pathName(path))}}
+	}
+	if s.Ref != "" {
+		return root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")].validateNode(root, n, path)
+	}
+	if len(s.OneOf) > 0 {
+		return s.validateOneOf(root, n, path)
+	}
+	if s.Type != "" && !nodeIs(n, s.Type) {
+		return []schemaError{{node: n, message: fmt.Sprintf("%s must be %s", pathName(path), typeName(s.Type))}}
+	}
+
+	errs := []schemaError{}
+	if len(s.Enum) > 0 && !slices.Contains(s.Enum, n.Value) {
+		errs = append(errs, schemaError{node: n, message: fmt.Sprintf("%s %s must be one of %s", pathName(path), n.Value, strings.Join(s.Enum, ", "))})
+	}
+	if s.pattern != nil && !s.pattern.MatchString(n.Value) {
+		errs = append(errs, schemaError{node: n, message: fmt.Sprintf("%s %q must match %s", pathName(path), n.Value, s.Pattern)})
+	}
+	if s.Minimum != nil {
+		if i, err := strconv.Atoi(n.Value); err == nil && i < *s.Minimum {
+			errs = append(errs, schemaError{node: n, message: fmt.Sprintf("%s must be %d or greater", pathName(path), *s.Minimum)})
+		}
+	}
+
+	switch n.Kind {
+	case yaml.MappingNode:
+		for _, r := range s.Required {
+			if valueOf(n, r) == nil {
+				errs = append(errs, schemaError{node: n, message: fmt.Sprintf("%s must have %s", pathName(path), r)})
+			}
+		}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			k, val := n.Content[i], n.Content[i+1]
+			if k.Value == "<<" {
+				// merged content is validated where it is defined
+				continue
+			}
+			if ps, ok := s.Properties[k.Value]; ok {
+				errs = append(errs, ps.validateNode(root, val, joinPath(path, k.Value))...)
+				continue
+			}
+			if s.AdditionalProperties == nil {
+				continue
+			}
+			if s.AdditionalProperties.never || !s.AdditionalProperties.typeMatches(root, val) {
+				// properties of mapping is known so that it is rather unknown key than invalid additional property
+				if len(s.Properties) > 0 {
+					message := fmt.Sprintf("unknown key %q", k.Value)
+					if path != "" {
+						message = fmt.Sprintf("unknown key %q in %s", k.Value, path)
+					}
+					if !s.AdditionalProperties.never {
+						message = fmt.Sprintf("%s. %s", message, s.AdditionalProperties.hint(root))
+					}
+					errs = append(errs, schemaError{node: k, message: message, parent: n, key: k.Value})
+					continue
+				}
+			}
+			errs = append(errs, s.AdditionalProperties.validateNode(root, val, joinPath(path, k.Value))...)
+		}
+	case yaml.SequenceNode:
+		if s.Items != nil {
+			for i, item := range n.Content {
+				errs = append(errs, s.Items.validateNode(root, item, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return errs
+}
+
+// validateOneOf validates the node against one of the schemas. errors of the schema that type of node matches are returned
+func (s *JSONSchema) validateOneOf(root *JSONSchema, n *yaml.Node, path string) []schemaError {
+	types := []string{}
+	for _, o := range s.OneOf {
+		if o.typeMatches(root, n) {
+			return o.validateNode(root, n, path)
+		}
+		types = append(types, typeName(o.resolve(root).Type))
+	}
+	return []schemaError{{node: n, message: fmt.Sprintf("%s must be one of %s", pathName(path), strings.Join(types, ", "))}}
+}
+
+// resolve returns the schema that $ref refers
+func (s *JSONSchema) resolve(root *JSONSchema) *JSONSchema {
+	if s.Ref != "" {
+		return root.Defs[strings.TrimPrefix(s.Ref, "#/$defs/")]
+	}
+	return s
+}
+
+// typeMatches returns whether type of the node matches the schema
+func (s *JSONSchema) typeMatches(root *JSONSchema, n *yaml.Node) bool {
+	s = s.resolve(root)
+	return s.Type == "" || nodeIs(resolveAlias(n), s.Type)
+}
+
+// hint returns what the value of the schema must be. e.g. profile must be a mapping
+func (s *JSONSchema) hint(root *JSONSchema) string {
+	name := "value"
+	if s.Ref != "" {
+		name = strings.ToLower(strings.TrimPrefix(s.Ref, "#/$defs/"))
+	}
+	return fmt.Sprintf("%s must be %s", name, typeName(s.resolve(root).Type))
+}
+
+// nodeIs returns whether the node is the JSON type. scalar is string only if it is resolved as string like editors do,
+// so that number and boolean must be quoted to be a string. e.g. value: "1"
+func nodeIs(n *yaml.Node, typ string) bool {
+	switch typ {
+	case "object":
+		return n.Kind == yaml.MappingNode
+	case "array":
+		return n.Kind == yaml.SequenceNode
+	case "string":
+		return n.Kind == yaml.ScalarNode && n.Tag == "!!str"
+	case "integer":
+		return n.Kind == yaml.ScalarNode && n.Tag == "!!int"
+	case "boolean":
+		return n.Kind == yaml.ScalarNode && n.Tag == "!!bool"
+	}
+	return true
+}
+
+// typeName returns the name of JSON type in the messages
+func typeName(typ string) string {
+	switch typ {
+	case "object":
+		return "a mapping"
+	case "array":
+		return "a list"
+	case "integer":
+		return "an integer"
+	case "":
+		return "any value"
+	}
+	return "a " + typ
+}
+
+// joinPath joins path of parent node and key with dot
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// pathName returns the path in the messages. empty path is the config itself
+func pathName(path string) string {
+	if path == "" {
+		return "config"
+	}
+	return path
+}
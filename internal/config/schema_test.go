@@ -0,0 +1,84 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"gopkg.in/yaml.v3"
+)
+
+var _ = Describe("Schema", func() {
+	schema := Schema()
+
+	// errors returns the messages of schema errors of the content
+	errors := func(s string) []string {
+		var doc yaml.Node
+		Expect(yaml.Unmarshal([]byte(s), &doc)).To(Succeed())
+		messages := []string{}
+		for _, e := range schema.validateNode(schema, doc.Content[0], "") {
+			messages = append(messages, e.message)
+		}
+		return messages
+	}
+
+	It("should be same as the golden file", func() {
+		b, err := json.MarshalIndent(schema, "", "  ")
+		Expect(err).NotTo(HaveOccurred())
+		file := filepath.Join("testdata", "golden", "schema.json")
+		if *updateGolden {
+			Expect(os.WriteFile(file, append(b, '\n'), 0644)).To(Succeed())
+		}
+		expected, err := os.ReadFile(file)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(b) + "\n").To(Equal(string(expected)))
+	})
+
+	It("should have all the yaml keys of the types", func() {
+		for name, t := range map[string]reflect.Type{"Profile": reflect.TypeOf(Profile{}), "Env": reflect.TypeOf(Env{}), "ValueFrom": reflect.TypeOf(ValueFrom{})} {
+			for i := 0; i < t.NumField(); i++ {
+				key, opts, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+				if key == "" || strings.Contains(opts, "inline") {
+					continue
+				}
+				Expect(schema.Defs[name].Properties).To(HaveKey(key))
+			}
+		}
+		Expect(schema.Properties).To(HaveKey("profiles"))
+	})
+
+	It("should refer profile schema for child profiles recursively", func() {
+		Expect(schema.Properties["profiles"].AdditionalProperties.Ref).To(Equal("#/$defs/Profile"))
+		Expect(schema.Defs["Profile"].AdditionalProperties.Ref).To(Equal("#/$defs/Profile"))
+		Expect(errors("profiles:\n  a:\n    b:\n      c:\n        env:\n        - name: VAR\n          value: VAL\n")).To(BeEmpty())
+		Expect(errors("profiles:\n  a:\n    b:\n      c:\n        env:\n        - value: VAL\n")).To(Equal([]string{"profiles.a.b.c.env[0] must have name"}))
+	})
+
+	It("should accept both shapes of init-script", func() {
+		Expect(errors("profiles:\n  a:\n    init-script: echo meow\n")).To(BeEmpty())
+		Expect(errors("profiles:\n  a:\n    init-script:\n    - run: echo meow\n")).To(BeEmpty())
+		Expect(errors("profiles:\n  a:\n    init-script: {run: echo meow}\n")).To(Equal([]string{"profiles.a.init-script must be one of a string, a list"}))
+	})
+
+	It("should accept only string scalars for string fields like the editors", func() {
+		Expect(errors("profiles:\n  a:\n    env:\n    - name: PORT\n      value: \"3\"\n")).To(BeEmpty())
+		Expect(errors("profiles:\n  a:\n    env:\n    - name: PORT\n      value: 3\n    - name: DEBUG\n      value: true\n")).To(Equal([]string{
+			"profiles.a.env[0].value must be a string",
+			"profiles.a.env[1].value must be a string",
+		}))
+	})
+
+	It("should validate types and values of the settings", func() {
+		Expect(errors("version: 0\nexpansion: bash\nvalue-timeout: 5\naudit:\n  allowlist: all\nbundle:\n  require-signature: yes please\n")).To(Equal([]string{
+			"version must be 1 or greater",
+			"expansion bash must be one of native, legacy",
+			"value-timeout must be a string",
+			"audit.allowlist must be a list",
+			"bundle.require-signature must be a boolean",
+		}))
+	})
+})
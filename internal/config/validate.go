@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// severities of Diagnostic
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// Diagnostic is a problem of config file at the position
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity string
+	Message  string
+}
+
+// String returns file:line:col: severity: message format of Diagnostic
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s", d.File, d.Line, d.Column, d.Severity, d.Message)
+}
+
+// yamlErrorPattern matches the line of yaml error message
+var yamlErrorPattern = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// validator collects diagnostics of config file
+type validator struct {
+	file        string
+	schema      *JSONSchema
+	diagnostics []Diagnostic
+	profiles    map[string]*yaml.Node   // key node of profile by name
+	envValues   map[string][]*yaml.Node // value node of env items of profile by name
+	unknown     map[*yaml.Node][]string // unknown keys of mapping nodes. they are pruned to check the rest of config
+}
+
+// ValidateContent validates the content of config file against the Schema and returns the diagnostics in order of position.
+// $VAR references are checked against the env vars of the profile and the current environment
+func ValidateContent(file string, b []byte) []Diagnostic {
+	v := &validator{
+		file:      file,
+		schema:    Schema(),
+		profiles:  map[string]*yaml.Node{},
+		envValues: map[string][]*yaml.Node{},
+		unknown:   map[*yaml.Node][]string{},
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(b, &doc); err != nil {
+		v.yamlError(err)
+		return v.diagnostics
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+
+	// structure of nodes
+	for _, e := range v.schema.validateNode(v.schema, root, "") {
+		v.errorf(e.node, "%s", e.message)
+		if e.parent != nil {
+			v.unknown[e.parent] = append(v.unknown[e.parent], e.key)
+		}
+	}
+	if profiles := valueOf(root, "profiles"); profiles != nil {
+		v.profileMap("", profiles)
+	}
+
+	// values of typed config
+	cfg, err := ParseConfig(b)
+	if err != nil && len(v.unknown) > 0 {
+		// unknown keys in profile fail to be decoded as child profile. check the rest of config without them
+		if pruned, perr := v.prune(&doc); perr == nil {
+			cfg, err = ParseConfig(pruned)
+		}
+	}
+	if err != nil {
+		if _, perr := ParseConfig(b); perr != nil {
+			v.yamlError(perr)
+		}
+		v.sort()
+		return v.diagnostics
+	}
+	if cfg.Version > CurrentVersion {
+		v.errorf(valueOf(root, "version"), "%v", NewConfigVersionNotSupportedError(cfg.Version))
+	}
+	v.defaultProfile(cfg, valueOf(root, "default"))
+	v.references(cfg)
+
+	v.sort()
+	return v.diagnostics
+}
+
+// prune removes the unknown keys of profiles from doc and returns the content of it
+func (v *validator) prune(doc *yaml.Node) ([]byte, error) {
+	for n, keys := range v.unknown {
+		content := []*yaml.Node{}
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			if !slices.Contains(keys, n.Content[i].Value) {
+				content = append(content, n.Content[i], n.Content[i+1])
+			}
+		}
+		n.Content = content
+	}
+	return yaml.Marshal(doc)
+}
+
+// errorf adds error diagnostic at the node
+func (v *validator) errorf(n *yaml.Node, format string, args ...interface{}) {
+	v.add(n, SeverityError, fmt.Sprintf(format, args...))
+}
+
+// warnf adds warning diagnostic at the node
+func (v *validator) warnf(n *yaml.Node, format string, args ...interface{}) {
+	v.add(n, SeverityWarning, fmt.Sprintf(format, args...))
+}
+
+// add adds diagnostic at the node. duplicated one is ignored
+func (v *validator) add(n *yaml.Node, severity, message string) {
+	d := Diagnostic{File: v.file, Severity: severity, Message: message}
+	if n != nil {
+		d.Line, d.Column = n.Line, n.Column
+	}
+	for _, e := range v.diagnostics {
+		if e == d {
+			return
+		}
+	}
+	v.diagnostics = append(v.diagnostics, d)
+}
+
+// yamlError adds diagnostics of yaml error with the line in the message
+func (v *validator) yamlError(err error) {
+	var messages []string
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		messages = typeErr.Errors
+	} else {
+		messages = []string{err.Error()}
+	}
+	for _, m := range messages {
+		d := Diagnostic{File: v.file, Severity: SeverityError, Message: strings.TrimPrefix(m, "yaml: ")}
+		if match := yamlErrorPattern.FindStringSubmatch(m); match != nil {
+			d.Line, _ = strconv.Atoi(match[1])
+			d.Message = match[2]
+		}
+		v.diagnostics = append(v.diagnostics, d)
+	}
+}
+
+// sort sorts diagnostics in order of position
+func (v *validator) sort() {
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+}
+
+// profileMap collects profiles in mapping of profiles. structure of nodes is validated with the schema
+func (v *validator) profileMap(prefix string, n *yaml.Node) {
+	n = resolveAlias(n)
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if k := n.Content[i]; k.Value != "<<" {
+			v.profile(joinPath(prefix, k.Value), k, n.Content[i+1])
+		}
+	}
+}
+
+// profile collects env items of profile and its child profiles
+func (v *validator) profile(name string, key, n *yaml.Node) {
+	v.profiles[name] = key
+	n = resolveAlias(n)
+	if n.Kind != yaml.MappingNode {
+		return
+	}
+
+	fields := v.schema.Defs["Profile"].Properties
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		k, val := n.Content[i], n.Content[i+1]
+		switch {
+		case k.Value == "<<":
+		case k.Value == "env":
+			v.envs(name, val)
+		case fields[k.Value] != nil:
+		case resolveAlias(val).Kind == yaml.MappingNode:
+			v.profile(joinPath(name, k.Value), k, val)
+		}
+	}
+}
+
+// envs collects value nodes of env items and validates that names are not duplicated
+func (v *validator) envs(profile string, n *yaml.Node) {
+	n = resolveAlias(n)
+	if n.Kind != yaml.SequenceNode {
+		return
+	}
+
+	seen := map[string]*yaml.Node{}
+	values := []*yaml.Node{}
+	for _, item := range n.Content {
+		item = resolveAlias(item)
+		if item.Kind != yaml.MappingNode {
+			continue
+		}
+
+		value := valueOf(item, "value")
+		if from := valueOf(item, "value-from"); from != nil {
+			value = from
+		}
+		if value == nil {
+			value = item
+		}
+		values = append(values, value)
+
+		name := valueOf(item, "name")
+		if name == nil || name.Value == "" {
+			continue
+		}
+		if seen[name.Value] != nil {
+			v.errorf(name, "duplicate env var %s in profile %s. it is defined at line %d already", name.Value, profile, seen[name.Value].Line)
+			continue
+		}
+		seen[name.Value] = name
+	}
+	v.envValues[profile] = values
+}
+
+// defaultProfile validates that default profile is existing and runnable
+func (v *validator) defaultProfile(cfg *Config, n *yaml.Node) {
+	if cfg.Default == "" {
+		return
+	}
+	if _, err := cfg.Profile(cfg.Default); err != nil {
+		v.errorf(n, "default profile %s is not existing", cfg.Default)
+		return
+	}
+	for _, name := range cfg.ProfileNames() {
+		if name == cfg.Default {
+			return
+		}
+	}
+	v.errorf(n, "default profile %s is a group that has no env", cfg.Default)
+}
+
+// references validates env items and their $VAR references.
+// env var must be defined in the resolved profile, its child profiles or the current environment
+func (v *validator) references(cfg *Config) {
+	cfg.Profiles.Walk(func(name string, p *Profile) {
+		values := v.envValues[name]
+		if len(values) != len(p.Env) {
+			// env is merged from other node with merge key
+			values = make([]*yaml.Node, len(p.Env))
+			for i := range values {
+				values[i] = v.profiles[name]
+			}
+		}
+
+		resolved, err := cfg.Profiles.ResolveProfile(name)
+		if err != nil {
+			v.errorf(v.profiles[name], "%v", err)
+		}
+		defined := map[string]bool{}
+		if resolved != nil {
+			for _, e := range resolved.Env {
+				defined[e.Name] = true
+			}
+		}
+		p.Profiles.Walk(func(_ string, child *Profile) {
+			for _, e := range child.Env {
+				defined[e.Name] = true
+			}
+		})
+
+		for i, e := range p.Env {
+			// op and the other fields are validated with the schema
+			if e.ValueFrom != nil {
+				if err := e.ValueFrom.Validate(e.Name); err != nil {
+					v.errorf(values[i], "%v", err)
+				}
+			}
+			if resolved == nil {
+				continue
+			}
+			for _, ref := range e.References() {
+				if _, ok := os.LookupEnv(ref); ok || defined[ref] {
+					continue
+				}
+				v.warnf(values[i], "$%s in %s of profile %s is not defined in the profile or the environment", ref, e.Name, name)
+			}
+		}
+	})
+}
+
+// valueOf returns value node of the key in mapping node
+func valueOf(n *yaml.Node, key string) *yaml.Node {
+	n = resolveAlias(n)
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// resolveAlias returns the node that alias node refers
+func resolveAlias(n *yaml.Node) *yaml.Node {
+	for n.Kind == yaml.AliasNode && n.Alias != nil {
+		n = n.Alias
+	}
+	return n
+}
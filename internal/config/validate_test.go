@@ -0,0 +1,114 @@
+package config
+
+import (
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateContent", func() {
+	messages := func(ds []Diagnostic) []string {
+		s := []string{}
+		for _, d := range ds {
+			s = append(s, d.String())
+		}
+		return s
+	}
+
+	When("config file is valid", func() {
+		It("should have no diagnostics", func() {
+			b := []byte("default: a\nprofiles:\n  a:\n    env:\n    - name: VAR\n      value: VAL\n    init-script:\n    - run: echo meow\n")
+			Expect(ValidateContent("config.yaml", b)).To(BeEmpty())
+		})
+	})
+
+	When("validate testdata/config.yaml", func() {
+		It("should report only init-script items without run", func() {
+			b, _ := os.ReadFile("../../testdata/config.yaml")
+			for _, d := range ValidateContent("config.yaml", b) {
+				Expect(d.Message).To(MatchRegexp(`^profiles\.[a-z-]+\.init-script\[\d\] must have run$|^unknown key "something-else" in profiles\.[a-z-]+\.init-script\[\d\]$`))
+			}
+		})
+	})
+
+	When("config file has problems", func() {
+		It("should report them with line and column", func() {
+			GinkgoT().Setenv("ENVP_TEST_DEFINED", "1")
+			os.Unsetenv("ENVP_TEST_UNDEFINED")
+			b := []byte(`default: group
+defualt: typo
+profiles:
+  group:
+    desc: group only
+    child:
+      env:
+      - name: 1INVALID
+        value: a
+      - name: VAR
+        value: a
+      - name: VAR
+        value: $ENVP_TEST_DEFINED/$ENVP_TEST_UNDEFINED/$PARENT
+      - name: OTHER
+        valu: typo
+    envs:
+    - name: A
+  parent:
+    env:
+    - name: PARENT
+      value: $CHILD
+    child:
+      env:
+      - name: CHILD
+        value: child
+      init-script:
+      - run: echo ok
+      - script: echo wrong
+  broken:
+    extends: [missing]
+    env:
+    - name: OP
+      value: a
+      op: replace
+`)
+			Expect(messages(ValidateContent("config.yaml", b))).To(Equal([]string{
+				"config.yaml:1:10: error: default profile group is a group that has no env",
+				"config.yaml:2:1: error: unknown key \"defualt\"",
+				"config.yaml:8:15: error: profiles.group.child.env[0].name \"1INVALID\" must match ^[A-Za-z_][A-Za-z0-9_]*$",
+				"config.yaml:12:15: error: duplicate env var VAR in profile group.child. it is defined at line 10 already",
+				"config.yaml:13:16: warning: $ENVP_TEST_UNDEFINED in VAR of profile group.child is not defined in the profile or the environment",
+				"config.yaml:13:16: warning: $PARENT in VAR of profile group.child is not defined in the profile or the environment",
+				"config.yaml:15:9: error: unknown key \"valu\" in profiles.group.child.env[3]",
+				"config.yaml:16:5: error: unknown key \"envs\" in profiles.group. profile must be a mapping",
+				"config.yaml:28:9: error: profiles.parent.child.init-script[1] must have run",
+				"config.yaml:28:9: error: unknown key \"script\" in profiles.parent.child.init-script[1]",
+				"config.yaml:29:3: error: profile broken extends missing that is not existing",
+				"config.yaml:34:11: error: profiles.broken.env[0].op replace must be one of set, prepend, append, unset",
+			}))
+		})
+	})
+
+	When("profile is null", func() {
+		It("should report that profile must be a mapping", func() {
+			b := []byte("default: a\nprofiles:\n  a:\n    env:\n    - name: A\n      value: a\n  nullprof:\n")
+			Expect(messages(ValidateContent("config.yaml", b))).To(Equal([]string{
+				"config.yaml:7:12: error: profiles.nullprof must be a mapping",
+			}))
+		})
+	})
+
+	When("config file is not valid yaml", func() {
+		It("should report the line of yaml error", func() {
+			ds := ValidateContent("config.yaml", []byte("default: a\nprofiles:\n  a:\n    env: [\n"))
+			Expect(ds).To(HaveLen(1))
+			Expect(ds[0].Line).To(BeNumerically(">", 0))
+			Expect(ds[0].Severity).To(Equal(SeverityError))
+		})
+
+		It("should report the line of type error", func() {
+			ds := ValidateContent("config.yaml", []byte("default: a\nprofiles:\n  a:\n    env: wrong\n"))
+			Expect(messages(ds)).To(ContainElement(Equal("config.yaml:4:10: error: profiles.a.env must be a list")))
+			Expect(messages(ds)).To(ContainElement(HavePrefix("config.yaml:4:0: error: cannot unmarshal")))
+		})
+	})
+})
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// kinds of ValueFrom
+const (
+	ValueFromFile     = "file"     // read the value from file
+	ValueFromEnv      = "env"      // copy the value of other env var of parent environment
+	ValueFromCommand  = "command"  // output of command that is executed without shell
+	ValueFromProvider = "provider" // output of external provider executable `envp-provider-<name>`
+)
+
+// providerNamePattern is pattern of provider name. path separators and dots are not allowed
+// so that the executable of provider is always looked up in the PATH
+var providerNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValueFrom is source of the value of Env. only one of the sources must be set
+type ValueFrom struct {
+	File     string            `mapstructure:"file" yaml:"file,omitempty"`
+	Env      string            `mapstructure:"env" yaml:"env,omitempty"`
+	Command  []string          `mapstructure:"command" yaml:"command,omitempty,flow"`
+	Provider string            `mapstructure:"provider" yaml:"provider,omitempty"`
+	Params   map[string]string `mapstructure:"params" yaml:"params,omitempty"`
+}
+
+// ValueFromInvalidError is an error when ValueFrom has no source or multiple sources
+type ValueFromInvalidError struct {
+	name  string
+	kinds []string
+}
+
+// NewValueFromInvalidError create new ValueFromInvalidError
+func NewValueFromInvalidError(name string, kinds []string) *ValueFromInvalidError {
+	return &ValueFromInvalidError{
+		name:  name,
+		kinds: kinds,
+	}
+}
+
+// Error is to make ValueFromInvalidError errors
+func (e *ValueFromInvalidError) Error() string {
+	if len(e.kinds) == 0 {
+		return fmt.Sprintf("value-from of %s must have one of %s, %s, %s, %s", e.name, ValueFromFile, ValueFromEnv, ValueFromCommand, ValueFromProvider)
+	}
+	return fmt.Sprintf("value-from of %s must have only one source but has %s", e.name, strings.Join(e.kinds, ", "))
+}
+
+// ProviderNameInvalidError is an error when provider name of ValueFrom is not allowed
+type ProviderNameInvalidError struct {
+	name     string
+	provider string
+}
+
+// NewProviderNameInvalidError create new ProviderNameInvalidError
+func NewProviderNameInvalidError(name, provider string) *ProviderNameInvalidError {
+	return &ProviderNameInvalidError{
+		name:     name,
+		provider: provider,
+	}
+}
+
+// Error is to make ProviderNameInvalidError errors
+func (e *ProviderNameInvalidError) Error() string {
+	return fmt.Sprintf("provider %q of %s is not allowed. it must match %s", e.provider, e.name, providerNamePattern)
+}
+
+// kinds returns kinds of the sources that are set
+func (v *ValueFrom) kinds() []string {
+	var kinds []string
+	if v.File != "" {
+		kinds = append(kinds, ValueFromFile)
+	}
+	if v.Env != "" {
+		kinds = append(kinds, ValueFromEnv)
+	}
+	if len(v.Command) > 0 {
+		kinds = append(kinds, ValueFromCommand)
+	}
+	if v.Provider != "" {
+		kinds = append(kinds, ValueFromProvider)
+	}
+	return kinds
+}
+
+// Kind returns kind of the source. it is empty if ValueFrom is not valid
+func (v *ValueFrom) Kind() string {
+	if kinds := v.kinds(); len(kinds) == 1 {
+		return kinds[0]
+	}
+	return ""
+}
+
+// Validate checks if ValueFrom has only one source and provider name is allowed. name is name of Env to be used in the error message
+func (v *ValueFrom) Validate(name string) error {
+	if kinds := v.kinds(); len(kinds) != 1 {
+		return NewValueFromInvalidError(name, kinds)
+	}
+	if v.Provider != "" && !providerNamePattern.MatchString(v.Provider) {
+		return NewProviderNameInvalidError(name, v.Provider)
+	}
+	return nil
+}
+
+// String returns string representation of the source. e.g. file:~/.secret, command:[cat ~/.secret]
+func (v *ValueFrom) String() string {
+	switch v.Kind() {
+	case ValueFromFile:
+		return fmt.Sprint(ValueFromFile, ":", v.File)
+	case ValueFromEnv:
+		return fmt.Sprint(ValueFromEnv, ":", v.Env)
+	case ValueFromCommand:
+		return fmt.Sprint(ValueFromCommand, ":", v.Command)
+	case ValueFromProvider:
+		// params in sorted order to make it stable
+		keys := make([]string, 0, len(v.Params))
+		for k := range v.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := []string{}
+		for _, k := range keys {
+			params = append(params, fmt.Sprint(k, "=", v.Params[k]))
+		}
+		return fmt.Sprintf("%s:%s{%s}", ValueFromProvider, v.Provider, strings.Join(params, ","))
+	}
+	return ""
+}
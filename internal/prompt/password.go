@@ -0,0 +1,35 @@
+package prompt
+
+import (
+	"io"
+
+	"github.com/manifoldco/promptui"
+)
+
+// PromptPassword prompts the masked input such as passphrase and secret value
+type PromptPassword struct {
+	prompt *promptui.Prompt
+	label  string
+}
+
+// NewPromptPassword create new PromptPassword
+func NewPromptPassword(label string) PromptPassword {
+	return PromptPassword{
+		label: label,
+		prompt: &promptui.Prompt{
+			Label: label,
+			Mask:  '*',
+		},
+	}
+}
+
+// SetIn sets the source for input data
+// If newIn is nil, os.Stdin is used.
+func (p *PromptPassword) SetIn(in io.Reader) {
+	p.prompt.Stdin = io.NopCloser(in)
+}
+
+// Prompt ask the input. input is not echoed
+func (p *PromptPassword) Prompt() (string, error) {
+	return p.prompt.Run()
+}
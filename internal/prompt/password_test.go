@@ -0,0 +1,18 @@
+package prompt
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPromptPassword(t *testing.T) {
+	prom := NewPromptPassword("label")
+	prom.SetIn(strings.NewReader("my-secret\n"))
+	s, err := prom.Prompt()
+	if err != nil {
+		t.Error(err)
+	}
+	if s != "my-secret" {
+		t.Errorf("expected my-secret but %s", s)
+	}
+}
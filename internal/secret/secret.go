@@ -0,0 +1,199 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	// Prefix is prefix of the encrypted value. e.g. enc:v1:base64(salt|nonce|ciphertext)
+	Prefix = "enc:v1:"
+
+	saltSize   = 16
+	keySize    = 32 // AES-256
+	iterations = 600000
+)
+
+// KeySource returns the passphrase that the key is derived from
+type KeySource func() (string, error)
+
+// DecryptError is an error when the encrypted value cannot be decrypted
+type DecryptError struct {
+	reason string
+}
+
+// NewDecryptError create new DecryptError
+func NewDecryptError(reason string) *DecryptError {
+	return &DecryptError{
+		reason: reason,
+	}
+}
+
+// Error is to make DecryptError errors
+func (e *DecryptError) Error() string {
+	return fmt.Sprintf("failed to decrypt the value: %s", e.reason)
+}
+
+// IsEncrypted checks if the value is encrypted value of supported version. other value starting with `enc:` is plaintext
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Cipher encrypts and decrypts the values with AES-GCM. key is derived from the passphrase of KeySource with PBKDF2.
+// passphrase is loaded only once when it is needed first time
+type Cipher struct {
+	source     KeySource
+	once       sync.Once
+	passphrase string
+	err        error
+
+	mu   sync.Mutex
+	salt []byte            // salt for encryption. values encrypted by same Cipher share the salt to derive the key only once
+	keys map[string][]byte // derived keys by salt
+}
+
+// NewCipher create new Cipher with KeySource
+func NewCipher(source KeySource) *Cipher {
+	return &Cipher{
+		source: source,
+		keys:   map[string][]byte{},
+	}
+}
+
+// loadPassphrase loads the passphrase from the KeySource once
+func (c *Cipher) loadPassphrase() (string, error) {
+	c.once.Do(func() {
+		c.passphrase, c.err = c.source()
+		if c.err == nil && c.passphrase == "" {
+			c.err = errors.New("passphrase is empty")
+		}
+	})
+	return c.passphrase, c.err
+}
+
+// key derives the key of salt
+func (c *Cipher) key(salt []byte) ([]byte, error) {
+	passphrase, err := c.loadPassphrase()
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if k, ok := c.keys[string(salt)]; ok {
+		return k, nil
+	}
+	k, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, keySize)
+	if err != nil {
+		return nil, err
+	}
+	c.keys[string(salt)] = k
+	return k, nil
+}
+
+// encryptionSalt returns the salt for encryption that is generated once
+func (c *Cipher) encryptionSalt() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.salt == nil {
+		salt := make([]byte, saltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return nil, err
+		}
+		c.salt = salt
+	}
+	return c.salt, nil
+}
+
+// Encrypt encrypts the plaintext and returns it in format of enc:v1:...
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	salt, err := c.encryptionSalt()
+	if err != nil {
+		return "", err
+	}
+	gcm, err := c.gcm(salt)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	data := append(append([]byte{}, salt...), nonce...)
+	data = gcm.Seal(data, nonce, []byte(plaintext), nil)
+	return Prefix + base64.RawStdEncoding.EncodeToString(data), nil
+}
+
+// Decrypt decrypts the value that is encrypted by Encrypt
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !strings.HasPrefix(value, Prefix) {
+		return "", NewDecryptError("unsupported format")
+	}
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(value, Prefix))
+	if err != nil {
+		return "", NewDecryptError("invalid encoding")
+	}
+	if len(data) < saltSize {
+		return "", NewDecryptError("too short")
+	}
+	salt := data[:saltSize]
+	gcm, err := c.gcm(salt)
+	if err != nil {
+		return "", err
+	}
+	data = data[saltSize:]
+	if len(data) < gcm.NonceSize() {
+		return "", NewDecryptError("too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", NewDecryptError("wrong key or corrupted value")
+	}
+	return string(plaintext), nil
+}
+
+// gcm creates AES-GCM with key of salt
+func (c *Cipher) gcm(salt []byte) (cipher.AEAD, error) {
+	key, err := c.key(salt)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Passphrase is KeySource of the passphrase
+func Passphrase(passphrase string) KeySource {
+	return func() (string, error) {
+		return passphrase, nil
+	}
+}
+
+// KeyFile is KeySource that reads the passphrase from the file. the file must not be accessible by group and others
+func KeyFile(name string) KeySource {
+	return func() (string, error) {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return "", err
+		}
+		if fi.Mode().Perm()&0077 != 0 {
+			return "", fmt.Errorf("key file %s is accessible by group or others (%v). it should be 0600 or 0400", name, fi.Mode().Perm())
+		}
+		b, err := os.ReadFile(name)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	}
+}
@@ -0,0 +1,66 @@
+package secret
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCipher(t *testing.T) {
+	c := NewCipher(Passphrase("my-passphrase"))
+
+	encrypted, err := c.Encrypt("my-secret")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encrypted, Prefix))
+	assert.True(t, IsEncrypted(encrypted))
+	assert.False(t, IsEncrypted("enc:foo"))
+	assert.False(t, IsEncrypted("enc:v0:abcd"))
+	assert.NotContains(t, encrypted, "my-secret")
+
+	// nonce is random for each encryption
+	again, _ := c.Encrypt("my-secret")
+	assert.NotEqual(t, encrypted, again)
+
+	// other Cipher with same passphrase can decrypt
+	decrypted, err := NewCipher(Passphrase("my-passphrase")).Decrypt(encrypted)
+	assert.NoError(t, err)
+	assert.Equal(t, "my-secret", decrypted)
+
+	var decryptErr *DecryptError
+	_, err = NewCipher(Passphrase("wrong")).Decrypt(encrypted)
+	assert.ErrorAs(t, err, &decryptErr)
+	_, err = c.Decrypt(encrypted[:len(encrypted)-4])
+	assert.ErrorAs(t, err, &decryptErr)
+	_, err = c.Decrypt("enc:v0:abcd")
+	assert.ErrorAs(t, err, &decryptErr)
+}
+
+func TestCipherLoadsKeyOnce(t *testing.T) {
+	loaded := 0
+	c := NewCipher(func() (string, error) {
+		loaded++
+		return "my-passphrase", nil
+	})
+	encrypted, _ := c.Encrypt("a")
+	_, _ = c.Decrypt(encrypted)
+	assert.Equal(t, 1, loaded)
+
+	_, err := NewCipher(Passphrase("")).Encrypt("a")
+	assert.Error(t, err)
+}
+
+func TestKeyFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "key")
+	assert.NoError(t, os.WriteFile(name, []byte("key-in-file\n"), 0600))
+	p, err := KeyFile(name)()
+	assert.NoError(t, err)
+	assert.Equal(t, "key-in-file", p)
+
+	assert.NoError(t, os.Chmod(name, 0644))
+	_, err = KeyFile(name)()
+	assert.Error(t, err)
+}
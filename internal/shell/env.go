@@ -0,0 +1,155 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+// layers of Environment. later layer wins.
+const (
+	LayerParent  = "parent"  // environment variables of the parent process. os.Environ()
+	LayerProfile = "profile" // env of the profile
+	LayerRuntime = "runtime" // env that is given at runtime such as flags
+	LayerEnvp    = "envp"    // ENVP_* metadata such as ENVP_PROFILE
+)
+
+// Environment is environment variables for the child process that is built by layers.
+// precedence is parent < profile < runtime < envp. each name has only one value so that the child never sees duplicated keys.
+type Environment struct {
+	vars    map[string]string
+	sources map[string]string
+	secrets map[string]bool // names of secret env vars that are masked in debug output
+}
+
+// NewEnvironment creates Environment with environ as parent layer. environ is KEY=VAL format such as os.Environ()
+// the last one wins when environ has duplicated keys
+func NewEnvironment(environ []string) *Environment {
+	e := &Environment{
+		vars:    map[string]string{},
+		sources: map[string]string{},
+		secrets: map[string]bool{},
+	}
+	for _, s := range environ {
+		k, v, _ := strings.Cut(s, "=")
+		if k == "" {
+			continue
+		}
+		e.Set(LayerParent, k, v)
+	}
+	return e
+}
+
+// Set sets the value of name from the layer
+func (e *Environment) Set(layer, name, value string) {
+	e.vars[name] = value
+	e.sources[name] = layer
+	delete(e.secrets, name)
+}
+
+// Unset removes name from the Environment
+func (e *Environment) Unset(name string) {
+	delete(e.vars, name)
+	delete(e.sources, name)
+	delete(e.secrets, name)
+}
+
+// Lookup returns value of name and whether it is existing
+func (e *Environment) Lookup(name string) (string, bool) {
+	v, ok := e.vars[name]
+	return v, ok
+}
+
+// Source returns the layer that the value of name came from
+func (e *Environment) Source(name string) string {
+	return e.sources[name]
+}
+
+// Apply applies the op of envs in order as layer
+func (e *Environment) Apply(layer string, envs config.Envs) error {
+	for _, env := range envs {
+		if err := env.Validate(); err != nil {
+			return err
+		}
+		current, _ := e.Lookup(env.Name)
+		// value that is prepended or appended to secret value is still secret
+		secret := env.IsSecret() || (e.secrets[env.Name] && env.Operation() != config.EnvOpSet)
+		value := env.Value
+		switch env.Operation() {
+		case config.EnvOpUnset:
+			e.Unset(env.Name)
+			continue
+		case config.EnvOpPrepend:
+			if current != "" {
+				value = value + env.Sep() + current
+			}
+		case config.EnvOpAppend:
+			if current != "" {
+				value = current + env.Sep() + value
+			}
+		}
+		e.Set(layer, env.Name, value)
+		if secret {
+			e.secrets[env.Name] = true
+		}
+	}
+	return nil
+}
+
+// Names returns names of environment variables in sorted order
+func (e *Environment) Names() []string {
+	names := make([]string, 0, len(e.vars))
+	for k := range e.vars {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Strings returns KEY=VAL slice of environment variables in sorted order of name
+func (e *Environment) Strings() []string {
+	r := []string{}
+	for _, k := range e.Names() {
+		r = append(r, k+"="+e.vars[k])
+	}
+	return r
+}
+
+// Debug returns the environment variables in sorted order with the layer that each value came from.
+// parent layer is skipped unless all is true. values of secret env vars are masked
+func (e *Environment) Debug(all bool) []string {
+	r := []string{}
+	for _, k := range e.Names() {
+		if !all && e.sources[k] == LayerParent {
+			continue
+		}
+		value := e.vars[k]
+		if e.secrets[k] {
+			value = config.MaskedValue
+		}
+		r = append(r, fmt.Sprintf("[%s] %s=%s", e.sources[k], k, value))
+	}
+	return r
+}
+
+// setEnvpProfile sets ENVP_PROFILE to leverage profile info in the shell prompt, such as starship.
+// it is conjunction with the value of parent with ">" to indicate profiles are nested
+func (e *Environment) setEnvpProfile(profile string) {
+	if value, exists := e.Lookup(envpEnvVarKey); exists && e.Source(envpEnvVarKey) == LayerParent {
+		e.Set(LayerEnvp, envpEnvVarKey, fmt.Sprintf("%s > %s", value, profile))
+	} else {
+		e.Set(LayerEnvp, envpEnvVarKey, profile)
+	}
+}
+
+// profileEnvironment creates Environment of parent and profile layers
+func profileEnvironment(envs config.Envs) (*Environment, error) {
+	environment := NewEnvironment(os.Environ())
+	if err := environment.Apply(LayerProfile, envs); err != nil {
+		return nil, err
+	}
+	return environment, nil
+}
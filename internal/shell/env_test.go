@@ -0,0 +1,129 @@
+package shell
+
+import (
+	"bytes"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("Environment", func() {
+
+	environ := []string{"PATH=/usr/bin:/bin", "NO_PROXY=localhost", "TO_BE_UNSET=meow", "DUP=1", "DUP=2"}
+
+	When("envs have ops", func() {
+		environment := NewEnvironment(environ)
+		err := environment.Apply(LayerProfile, config.Envs{
+			{Name: "PATH", Value: "/opt/bin", Op: config.EnvOpPrepend},
+			{Name: "NO_PROXY", Value: ".corp", Op: config.EnvOpAppend, Separator: ","},
+			{Name: "TO_BE_UNSET", Op: config.EnvOpUnset},
+			{Name: "NEW_VAR", Value: "VAL"},
+			{Name: "NEW_PATH", Value: "/opt/bin", Op: config.EnvOpAppend},
+		})
+
+		It("should not occur error", func() {
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should apply ops against existing environment variables in sorted order", func() {
+			Expect(environment.Strings()).To(Equal([]string{
+				"DUP=2",
+				"NEW_PATH=/opt/bin",
+				"NEW_VAR=VAL",
+				"NO_PROXY=localhost,.corp",
+				"PATH=/opt/bin:/usr/bin:/bin",
+			}))
+		})
+	})
+
+	When("op is not supported", func() {
+		environment := NewEnvironment(environ)
+		err := environment.Apply(LayerProfile, config.Envs{
+			{Name: "PATH", Value: "/opt/bin", Op: "meow"},
+		})
+
+		It("should occur error", func() {
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	When("layers have same key", func() {
+		environment := NewEnvironment([]string{"VAR=parent", "PARENT_ONLY=parent", envpEnvVarKey + "=runtime"})
+		environment.Apply(LayerProfile, config.Envs{{Name: "VAR", Value: "profile"}, {Name: "PROFILE_ONLY", Value: "profile"}})
+		environment.Apply(LayerRuntime, config.Envs{{Name: "VAR", Value: "runtime"}, {Name: envpEnvVarKey, Value: "runtime"}})
+		environment.setEnvpProfile("my-profile")
+
+		It("later layer should win", func() {
+			Expect(environment.Strings()).To(Equal([]string{
+				envpEnvVarKey + "=my-profile",
+				"PARENT_ONLY=parent",
+				"PROFILE_ONLY=profile",
+				"VAR=runtime",
+			}))
+		})
+
+		It("debug view should show the layer of each key", func() {
+			Expect(environment.Debug(false)).To(Equal([]string{
+				fmt.Sprintf("[%s] %s=my-profile", LayerEnvp, envpEnvVarKey),
+				fmt.Sprintf("[%s] PROFILE_ONLY=profile", LayerProfile),
+				fmt.Sprintf("[%s] VAR=runtime", LayerRuntime),
+			}))
+			Expect(environment.Debug(true)).To(ContainElement(fmt.Sprintf("[%s] PARENT_ONLY=parent", LayerParent)))
+		})
+	})
+})
+
+var _ = Describe("Environment with secret", func() {
+	environment := NewEnvironment([]string{"PATH=/usr/bin", "TOKEN=parent-token"})
+	err := environment.Apply(LayerProfile, config.Envs{
+		{Name: "PASSWORD", Value: "my-password", Secret: true},
+		{Name: "PASSWORD", Value: "suffix", Op: config.EnvOpAppend},
+		{Name: "PATH", Value: "/secret/bin", Op: config.EnvOpPrepend, Secret: true},
+		{Name: "TOKEN", Value: "not-secret"},
+	})
+
+	It("should mask the secret values in debug view", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(environment.Debug(false)).To(Equal([]string{
+			fmt.Sprintf("[%s] PASSWORD=%s", LayerProfile, config.MaskedValue),
+			fmt.Sprintf("[%s] PATH=%s", LayerProfile, config.MaskedValue),
+			fmt.Sprintf("[%s] TOKEN=not-secret", LayerProfile),
+		}))
+	})
+
+	It("should not mask the values for the child process", func() {
+		Expect(environment.Strings()).To(ContainElements("PASSWORD=my-password:suffix", "PATH=/secret/bin:/usr/bin"))
+	})
+})
+
+var _ = Describe("ShellCommand with runtime overrides", func() {
+
+	var stdout, stderr bytes.Buffer
+	sc := NewShellCommand()
+	sc.Stdout = &stdout
+	sc.Stderr = &stderr
+	sc.Overrides = config.Envs{{Name: "MY_VAR", Value: "runtime"}}
+	sc.DebugEnv = true
+
+	profile := config.NamedProfile{
+		Name:    "my-profile",
+		Profile: config.NewProfile(),
+	}
+	profile.Env = config.Envs{{Name: "MY_VAR", Value: "profile"}}
+
+	err := sc.Execute([]string{"/bin/sh", "-c", "echo $MY_VAR"}, &profile, false)
+
+	It("should not occur error", func() {
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("runtime override should win", func() {
+		Expect(stdout.String()).To(Equal("runtime\n"))
+	})
+
+	It("should print debug view into stderr", func() {
+		Expect(stderr.String()).To(ContainSubstring(fmt.Sprintf("[%s] MY_VAR=runtime", LayerRuntime)))
+	})
+})
@@ -0,0 +1,327 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/secret"
+)
+
+const (
+	// DefaultValueTimeout is default timeout of evaluating each env value
+	DefaultValueTimeout = 30 * time.Second
+	// DefaultTotalTimeout is default timeout of evaluating all the env values of profile
+	DefaultTotalTimeout = 2 * time.Minute
+	// waitDelay is delay to wait for the pipes of killed command to be closed
+	waitDelay = time.Second
+)
+
+// EvaluationFailure is failure of evaluating the value of env var
+type EvaluationFailure struct {
+	Name string
+	Err  error
+}
+
+// EvaluationError is an error of evaluating env values. it has the failures of all env vars
+type EvaluationError struct {
+	Failures []*EvaluationFailure
+}
+
+// Error is to make EvaluationError errors
+func (e *EvaluationError) Error() string {
+	s := []string{}
+	for _, f := range e.Failures {
+		s = append(s, fmt.Sprintf("[envp] error processing value of %s: %v", f.Name, f.Err))
+	}
+	return strings.Join(s, "\n")
+}
+
+// Unwrap returns errors of the failures
+func (e *EvaluationError) Unwrap() []error {
+	errs := []error{}
+	for _, f := range e.Failures {
+		errs = append(errs, f.Err)
+	}
+	return errs
+}
+
+// CommandError is an error of command that is executed to evaluate the value. it has exit status and stderr of the command
+type CommandError struct {
+	Command  string // description of the command. e.g. $(script) for command substitution
+	ExitCode int    // exit status of the command. -1 if command is not exited normally
+	Stderr   string
+	TimedOut bool
+	Err      error
+}
+
+// Error is to make CommandError errors
+func (e *CommandError) Error() string {
+	var msg string
+	switch {
+	case e.TimedOut:
+		msg = fmt.Sprintf("command %s timed out", e.Command)
+	case e.ExitCode >= 0:
+		msg = fmt.Sprintf("command %s failed with exit status %d", e.Command, e.ExitCode)
+	default:
+		msg = fmt.Sprintf("command %s failed: %v", e.Command, e.Err)
+	}
+	if e.Stderr != "" {
+		msg = fmt.Sprint(msg, ": ", e.Stderr)
+	}
+	return msg
+}
+
+// Unwrap returns the error of command
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// SecretValueError is an error of evaluating secret value. details are hidden since the error message may have the value
+type SecretValueError struct {
+	Err error
+}
+
+// Error is to make SecretValueError errors
+func (e *SecretValueError) Error() string {
+	var cmdErr *CommandError
+	switch {
+	case errors.As(e.Err, &cmdErr) && cmdErr.TimedOut:
+		return "command timed out (details of secret value are hidden)"
+	case errors.As(e.Err, &cmdErr) && cmdErr.ExitCode >= 0:
+		return fmt.Sprintf("command failed with exit status %d (details of secret value are hidden)", cmdErr.ExitCode)
+	}
+	return "failed to evaluate (details of secret value are hidden)"
+}
+
+// Unwrap returns the error of evaluation
+func (e *SecretValueError) Unwrap() error {
+	return e.Err
+}
+
+// ValueCache caches the evaluated values of env vars
+type ValueCache interface {
+	// Get returns cached value of expression of env var in the profile
+	Get(profile, name, expression string) (string, bool)
+	// Set caches value of expression of env var in the profile for ttl
+	Set(profile, name, expression, value string, ttl time.Duration) error
+}
+
+// Decrypter decrypts the encrypted env values
+type Decrypter interface {
+	Decrypt(value string) (string, error)
+}
+
+// valueEvaluator evaluates the value of env var with environment
+type valueEvaluator func(ctx context.Context, value string, environment *Environment) (string, error)
+
+// envEvaluator evaluates env values. independent values are evaluated concurrently
+type envEvaluator struct {
+	profile      string
+	legacy       bool
+	valueTimeout time.Duration
+	totalTimeout time.Duration
+	cache        ValueCache
+	secrets      Decrypter
+}
+
+// parseEnvs parse Env values with native expander
+func parseEnvs(envs config.Envs) error {
+	return (&envEvaluator{}).evaluate(envs)
+}
+
+// parseEnvsLegacy parse Env values with shell echo. it is kept for compatibility of the configs that rely on shell echo semantics
+func parseEnvsLegacy(envs config.Envs) error {
+	return (&envEvaluator{legacy: true}).evaluate(envs)
+}
+
+// evaluate evaluates Env values and updates the values.
+// values are evaluated by level of references so that env var can refer other env vars regardless of the declaration order.
+// values in same level are evaluated concurrently. value that is failed keeps its original value.
+// it returns EvaluationError that has all the failures
+func (v *envEvaluator) evaluate(envs config.Envs) error {
+	levels, err := envs.EvaluationLevels()
+	if err != nil {
+		return err
+	}
+
+	valueEvaluator := expandValue
+	if v.legacy {
+		valueEvaluator = echoValue
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), durationOrDefault(v.totalTimeout, DefaultTotalTimeout))
+	defer cancel()
+
+	evaluated := map[*config.Env]bool{}
+	var failures []*EvaluationFailure
+	for _, level := range levels {
+		// environment variables for the evaluation. only evaluated env vars are applied in declaration order
+		environment, err := profileEnvironment(evaluatedEnvs(envs, evaluated))
+		if err != nil {
+			return err
+		}
+
+		results := make([]string, len(level))
+		errs := make([]error, len(level))
+		var wg sync.WaitGroup
+		for i, e := range level {
+			// nothing to evaluate for unset
+			if e.Operation() == config.EnvOpUnset {
+				results[i] = e.Value
+				continue
+			}
+			wg.Add(1)
+			go func(i int, e *config.Env) {
+				defer wg.Done()
+				// encrypted value is decrypted in memory only. it is neither expanded nor cached
+				if secret.IsEncrypted(e.Value) {
+					results[i], errs[i] = v.decrypt(e.Value)
+					return
+				}
+				// use cached value if env var has cache ttl
+				cacheable := e.Cache > 0 && v.cache != nil
+				if cacheable {
+					if cached, ok := v.cache.Get(v.profile, e.Name, e.Expression()); ok {
+						results[i] = cached
+						return
+					}
+				}
+				vctx, vcancel := context.WithTimeout(ctx, durationOrDefault(v.valueTimeout, DefaultValueTimeout))
+				defer vcancel()
+				if e.ValueFrom != nil {
+					results[i], errs[i] = resolveValueFrom(vctx, v.profile, e, environment)
+				} else {
+					results[i], errs[i] = valueEvaluator(vctx, e.Value, environment)
+				}
+				if cacheable && errs[i] == nil {
+					// cache is best effort. failure of caching should not fail the evaluation
+					_ = v.cache.Set(v.profile, e.Name, e.Expression(), results[i], e.Cache)
+				}
+			}(i, e)
+		}
+		wg.Wait()
+
+		for i, e := range level {
+			// keep it secret after evaluation since decrypted value is not encrypted value anymore
+			if e.IsSecret() {
+				e.Secret = true
+			}
+			if errs[i] != nil {
+				if e.Secret {
+					errs[i] = &SecretValueError{Err: errs[i]}
+				}
+				failures = append(failures, &EvaluationFailure{Name: e.Name, Err: errs[i]})
+			} else {
+				e.Value = results[i]
+			}
+			evaluated[e] = true
+		}
+	}
+
+	if len(failures) > 0 {
+		return &EvaluationError{Failures: failures}
+	}
+	return nil
+}
+
+// decrypt decrypts the encrypted value with Decrypter
+func (v *envEvaluator) decrypt(value string) (string, error) {
+	if v.secrets == nil {
+		return "", errors.New("no key to decrypt the encrypted value")
+	}
+	return v.secrets.Decrypt(value)
+}
+
+// expandValue expands the value with native expander. command substitution is performed with environment
+func expandValue(ctx context.Context, value string, environment *Environment) (string, error) {
+	x := &expander{
+		lookup: environment.Lookup,
+		command: func(script string) (string, error) {
+			return runCommandSubstitution(ctx, script, environment.Strings())
+		},
+	}
+	return x.expand(value)
+}
+
+// echoValue parse the value with shell echo
+func echoValue(ctx context.Context, value string, environment *Environment) (string, error) {
+	// parse env value with shell echo
+	// set environment to cmd that runs command substitution as well to support the case that reuse env var as ref with substitution
+	cmd := scriptCommand(ctx, fmt.Sprintf("echo %s", value), environment.Strings())
+	cmd.WaitDelay = waitDelay
+
+	// it never occurs error since it is processed with shell echo.
+	// so that, it will not exit 1 even command substitution has error. and just print out empty line when it errors
+	output, _ := cmd.Output()
+	if ctx.Err() != nil {
+		return "", fmt.Errorf("evaluating %s timed out", value)
+	}
+	// trim new lines from result
+	result := strings.TrimRight(string(output), "\r\n")
+	// use os.ExpandEnv to replace all the ${var} or $var in the string according to the values of the current environment variables.
+	// so that $HOME will be replaced to current user's abs home dir
+	result = os.ExpandEnv(result)
+
+	if len(value) > 0 && len(result) == 0 {
+		return "", fmt.Errorf("empty result of %s", value)
+	}
+	return result, nil
+}
+
+// runCommandSubstitution runs script of command substitution with /bin/sh and environ. script never appears on the argv.
+// it returns stdout of command without trailing new lines.
+func runCommandSubstitution(ctx context.Context, script string, environ []string) (string, error) {
+	return runCommand(ctx, scriptCommand(ctx, script, environ), fmt.Sprintf("$(%s)", script))
+}
+
+// runCommand runs cmd that is created with ctx, and returns stdout of command without trailing new lines.
+// non-zero exit status and timeout are CommandError with stderr of command. name is description of command for the error
+func runCommand(ctx context.Context, cmd *exec.Cmd, name string) (string, error) {
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	cmd.WaitDelay = waitDelay
+
+	out, err := cmd.Output()
+	if err != nil {
+		e := &CommandError{
+			Command:  name,
+			ExitCode: -1,
+			Stderr:   strings.TrimSpace(stderr.String()),
+			TimedOut: errors.Is(ctx.Err(), context.DeadlineExceeded),
+			Err:      err,
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && !e.TimedOut {
+			e.ExitCode = exitErr.ExitCode()
+		}
+		return "", e
+	}
+	return strings.TrimRight(string(out), "\r\n"), nil
+}
+
+// evaluatedEnvs returns env vars in envs that are evaluated already
+func evaluatedEnvs(envs config.Envs, evaluated map[*config.Env]bool) config.Envs {
+	r := config.Envs{}
+	for _, e := range envs {
+		if evaluated[e] {
+			r = append(r, e)
+		}
+	}
+	return r
+}
+
+// durationOrDefault returns d if it is greater than zero. otherwise def
+func durationOrDefault(d, def time.Duration) time.Duration {
+	if d > 0 {
+		return d
+	}
+	return def
+}
@@ -0,0 +1,198 @@
+package shell
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/secret"
+)
+
+var _ = Describe("envEvaluator", func() {
+
+	When("values have independent command substitutions", func() {
+		envs := config.Envs{}
+		envs.AddEnv("SLEEP_1", "$(sleep 0.5; echo 1)")
+		envs.AddEnv("SLEEP_2", "$(sleep 0.5; echo 2)")
+		envs.AddEnv("SLEEP_3", "$(sleep 0.5; echo 3)")
+		start := time.Now()
+		err := (&envEvaluator{}).evaluate(envs)
+		elapsed := time.Since(start)
+
+		It("should not occur error", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs.String()).To(Equal("SLEEP_1=1,SLEEP_2=2,SLEEP_3=3"))
+		})
+
+		It("should be evaluated concurrently", func() {
+			Expect(elapsed).To(BeNumerically("<", 1200*time.Millisecond))
+		})
+	})
+
+	When("command substitution is hanging", func() {
+		envs := config.Envs{}
+		envs.AddEnv("HANGING", "$(sleep 10)")
+		envs.AddEnv("OK", "ok")
+		start := time.Now()
+		err := (&envEvaluator{valueTimeout: 200 * time.Millisecond}).evaluate(envs)
+		elapsed := time.Since(start)
+
+		It("should be timed out", func() {
+			var cmdErr *CommandError
+			Expect(errors.As(err, &cmdErr)).To(BeTrue())
+			Expect(cmdErr.TimedOut).To(BeTrue())
+			Expect(elapsed).To(BeNumerically("<", 5*time.Second))
+		})
+
+		It("should evaluate other values", func() {
+			Expect(envs.String()).To(Equal("HANGING=$(sleep 10),OK=ok"))
+		})
+	})
+
+	When("total evaluation is timed out", func() {
+		envs := config.Envs{}
+		envs.AddEnv("FIRST", "$(sleep 0.3; echo first)")
+		envs.AddEnv("SECOND", "$(sleep 0.3; echo $FIRST)")
+		err := (&envEvaluator{totalTimeout: 400 * time.Millisecond}).evaluate(envs)
+
+		It("should be timed out", func() {
+			var evalErr *EvaluationError
+			Expect(errors.As(err, &evalErr)).To(BeTrue())
+			Expect(evalErr.Failures).To(HaveLen(1))
+			Expect(evalErr.Failures[0].Name).To(Equal("SECOND"))
+		})
+	})
+
+	When("command substitutions are failed", func() {
+		envs := config.Envs{}
+		envs.AddEnv("FAIL_1", "$(echo meow >&2; exit 3)")
+		envs.AddEnv("OK", "ok")
+		envs.AddEnv("FAIL_2", "$(not-existing-command-meow)")
+		err := (&envEvaluator{}).evaluate(envs)
+
+		It("should return aggregated error of all failures", func() {
+			var evalErr *EvaluationError
+			Expect(errors.As(err, &evalErr)).To(BeTrue())
+			Expect(evalErr.Failures).To(HaveLen(2))
+			Expect(evalErr.Failures[0].Name).To(Equal("FAIL_1"))
+			Expect(evalErr.Failures[1].Name).To(Equal("FAIL_2"))
+		})
+
+		It("should have exit status and stderr of the command", func() {
+			var cmdErr *CommandError
+			Expect(errors.As(err, &cmdErr)).To(BeTrue())
+			Expect(cmdErr.ExitCode).To(Equal(3))
+			Expect(cmdErr.Stderr).To(Equal("meow"))
+			Expect(err.Error()).To(ContainSubstring("error processing value of FAIL_1"))
+			Expect(err.Error()).To(ContainSubstring("exit status 3: meow"))
+			Expect(err.Error()).To(ContainSubstring("error processing value of FAIL_2"))
+		})
+	})
+})
+
+// memoryCache is ValueCache in memory for testing
+type memoryCache map[string]string
+
+func (m memoryCache) Get(profile, name, expression string) (string, bool) {
+	v, ok := m[profile+name+expression]
+	return v, ok
+}
+
+func (m memoryCache) Set(profile, name, expression, value string, ttl time.Duration) error {
+	m[profile+name+expression] = value
+	return nil
+}
+
+var _ = Describe("envEvaluator with cache", func() {
+
+	valueCache := memoryCache{}
+	evaluator := &envEvaluator{profile: "my-profile", cache: valueCache}
+
+	newEnvs := func() config.Envs {
+		return config.Envs{
+			{Name: "CACHED", Value: "$(date +%s%N)", Cache: time.Minute},
+			{Name: "NOT_CACHED", Value: "$(date +%s%N)"},
+		}
+	}
+
+	first := newEnvs()
+	errFirst := evaluator.evaluate(first)
+	second := newEnvs()
+	errSecond := evaluator.evaluate(second)
+
+	It("should not occur error", func() {
+		Expect(errFirst).NotTo(HaveOccurred())
+		Expect(errSecond).NotTo(HaveOccurred())
+	})
+
+	It("should reuse cached value for env var that has cache", func() {
+		Expect(second[0].Value).To(Equal(first[0].Value))
+		Expect(valueCache).To(HaveLen(1))
+	})
+
+	It("should evaluate again for env var that has no cache", func() {
+		Expect(second[1].Value).NotTo(Equal(first[1].Value))
+	})
+})
+
+var _ = Describe("encrypted value", func() {
+	cipher := secret.NewCipher(secret.Passphrase("my-passphrase"))
+	encrypted, _ := cipher.Encrypt("$NOT_EXPANDED")
+
+	When("Decrypter is set", func() {
+		envs := config.Envs{}
+		envs.AddEnv("SECRET", encrypted)
+		err := (&envEvaluator{secrets: cipher}).evaluate(envs)
+
+		It("should be decrypted without expansion", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs[0].Value).To(Equal("$NOT_EXPANDED"))
+		})
+	})
+
+	When("Decrypter is not set", func() {
+		envs := config.Envs{}
+		envs.AddEnv("SECRET", encrypted)
+		err := (&envEvaluator{}).evaluate(envs)
+
+		It("should be error", func() {
+			Expect(err).To(HaveOccurred())
+			Expect(envs[0].Value).To(Equal(encrypted))
+		})
+	})
+})
+
+var _ = Describe("secret value", func() {
+
+	When("evaluation of secret value is failed", func() {
+		envs := config.Envs{
+			{Name: "PASSWORD", Value: "$(echo my-password >&2; exit 3)", Secret: true},
+		}
+		err := (&envEvaluator{}).evaluate(envs)
+
+		It("should hide the details of error", func() {
+			var secretErr *SecretValueError
+			var cmdErr *CommandError
+			Expect(errors.As(err, &secretErr)).To(BeTrue())
+			Expect(errors.As(err, &cmdErr)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("exit status 3"))
+			Expect(err.Error()).NotTo(ContainSubstring("my-password"))
+		})
+	})
+
+	When("encrypted value is decrypted", func() {
+		cipher := secret.NewCipher(secret.Passphrase("my-passphrase"))
+		encrypted, _ := cipher.Encrypt("my-password")
+		envs := config.Envs{}
+		envs.AddEnv("PASSWORD", encrypted)
+		err := (&envEvaluator{secrets: cipher}).evaluate(envs)
+
+		It("should be secret", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(envs[0].Secret).To(BeTrue())
+			Expect(envs.String()).To(Equal("PASSWORD=" + config.MaskedValue))
+		})
+	})
+})
@@ -0,0 +1,205 @@
+package shell
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// expander expands the value of env var natively without shell.
+// only explicit command substitution $(...) runs a subprocess.
+//
+// supported syntax:
+//
+//	$VAR, ${VAR}      value of VAR. empty if it is not set
+//	${VAR:-default}   default if VAR is not set or empty
+//	${VAR:+alt}       alt if VAR is set and not empty
+//	${VAR:?message}   error with message if VAR is not set or empty
+//	$(command)        output of command. trailing new lines are trimmed
+//	~                 home dir of user. only at the start of the value
+//	\$ or $$          literal $
+//
+// quotes and glob characters have no special meaning. they are kept as is.
+type expander struct {
+	lookup  func(name string) (string, bool)    // lookup the value of env var
+	command func(script string) (string, error) // run command substitution
+}
+
+// expand expands s
+func (x *expander) expand(s string) (string, error) {
+	var b strings.Builder
+
+	// tilde at the start of value
+	if s == "~" || strings.HasPrefix(s, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(home)
+		s = s[1:]
+	}
+
+	for i := 0; i < len(s); {
+		switch {
+		case s[i] == '\\' && i+1 < len(s) && s[i+1] == '$':
+			b.WriteByte('$')
+			i += 2
+		case s[i] == '$':
+			n, err := x.expandDollar(s[i:], &b)
+			if err != nil {
+				return "", err
+			}
+			i += n
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+	return b.String(), nil
+}
+
+// expandDollar expands the expression that starts with $ at the beginning of s into b
+// it returns the length of expression in s
+func (x *expander) expandDollar(s string, b *strings.Builder) (int, error) {
+	if len(s) < 2 {
+		b.WriteByte('$')
+		return 1, nil
+	}
+
+	switch c := s[1]; {
+	case c == '$':
+		b.WriteByte('$')
+		return 2, nil
+
+	case c == '(':
+		end := matchingParen(s, 1)
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated command substitution: %s", s)
+		}
+		out, err := x.command(s[2:end])
+		if err != nil {
+			return 0, err
+		}
+		b.WriteString(out)
+		return end + 1, nil
+
+	case c == '{':
+		end := matchingBrace(s, 1)
+		if end < 0 {
+			return 0, fmt.Errorf("unterminated parameter expansion: %s", s)
+		}
+		v, err := x.expandBraces(s[2:end])
+		if err != nil {
+			return 0, err
+		}
+		b.WriteString(v)
+		return end + 1, nil
+
+	case isNameStart(c):
+		n := nameLength(s[1:])
+		v, _ := x.lookup(s[1 : 1+n])
+		b.WriteString(v)
+		return 1 + n, nil
+	}
+
+	b.WriteByte('$')
+	return 1, nil
+}
+
+// expandBraces expands body of ${...}
+func (x *expander) expandBraces(body string) (string, error) {
+	n := nameLength(body)
+	if n == 0 {
+		return "", fmt.Errorf("bad substitution: ${%s}", body)
+	}
+	name, rest := body[:n], body[n:]
+	value, _ := x.lookup(name)
+
+	switch {
+	case rest == "":
+		return value, nil
+	case strings.HasPrefix(rest, ":-"):
+		if value != "" {
+			return value, nil
+		}
+		return x.expand(rest[2:])
+	case strings.HasPrefix(rest, ":+"):
+		if value == "" {
+			return "", nil
+		}
+		return x.expand(rest[2:])
+	case strings.HasPrefix(rest, ":?"):
+		if value != "" {
+			return value, nil
+		}
+		msg := rest[2:]
+		if msg == "" {
+			msg = "parameter null or not set"
+		}
+		return "", fmt.Errorf("%s: %s", name, msg)
+	}
+	return "", fmt.Errorf("bad substitution: ${%s}", body)
+}
+
+// matchingParen returns index of ")" that matches "(" at start in s. quoted strings are skipped
+func matchingParen(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '\'', '"':
+			// skip quoted string
+			q := s[i]
+			for i++; i < len(s) && s[i] != q; i++ {
+				if q == '"' && s[i] == '\\' {
+					i++
+				}
+			}
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// matchingBrace returns index of "}" that matches "{" at start in s
+func matchingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// isNameStart returns whether c can be the first character of env var name
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// nameLength returns length of env var name at the beginning of s
+func nameLength(s string) int {
+	if len(s) == 0 || !isNameStart(s[0]) {
+		return 0
+	}
+	n := 1
+	for n < len(s) && (isNameStart(s[n]) || (s[n] >= '0' && s[n] <= '9')) {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,75 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("expander", func() {
+
+	environment := NewEnvironment([]string{"VAR=VAL", "EMPTY=", "SPACE=hello world"})
+	home, _ := os.UserHomeDir()
+
+	DescribeTable("expand the value",
+		func(value, expected string) {
+			actual, err := expandValue(context.Background(), value, environment)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(actual).To(Equal(expected))
+		},
+		Entry("plain", "plain value", "plain value"),
+		Entry("$VAR", "$VAR/x", "VAL/x"),
+		Entry("${VAR}", "${VAR}x", "VALx"),
+		Entry("undefined var", "a${NOT_DEFINED}b$NOT_DEFINED", "ab"),
+		Entry("default of undefined var", "${NOT_DEFINED:-default}", "default"),
+		Entry("default of empty var", "${EMPTY:-$VAR}", "VAL"),
+		Entry("default of defined var", "${VAR:-default}", "VAL"),
+		Entry("nested default", "${NOT_DEFINED:-${EMPTY:-nested}}", "nested"),
+		Entry("alternative value", "${VAR:+alt}${EMPTY:+alt}", "alt"),
+		Entry("error message of defined var", "${VAR:?must be set}", "VAL"),
+		Entry("tilde", "~/.config", fmt.Sprintf("%s/.config", home)),
+		Entry("tilde only", "~", home),
+		Entry("tilde in the middle", "a:~/b", "a:~/b"),
+		Entry("escaped dollar", `\$VAR $$VAR`, "$VAR $VAR"),
+		Entry("quotes and globs", `'single' "double" * ?`, `'single' "double" * ?`),
+		Entry("value with spaces", "$SPACE", "hello world"),
+		Entry("lonely dollar", "100$", "100$"),
+		Entry("command substitution", "$(echo hello)", "hello"),
+		Entry("command substitution with env", "$(echo $VAR)-$(printf '%s' \"(x)\")", "VAL-(x)"),
+		Entry("nested command substitution", "$(echo $(echo nested))", "nested"),
+	)
+
+	DescribeTable("error on expanding the value",
+		func(value string) {
+			_, err := expandValue(context.Background(), value, environment)
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("error message of undefined var", "${NOT_DEFINED:?must be set}"),
+		Entry("error message of empty var", "${EMPTY:?}"),
+		Entry("bad substitution", "${VAR%%x}"),
+		Entry("unterminated parameter expansion", "${VAR"),
+		Entry("unterminated command substitution", "$(echo"),
+		Entry("failed command", "$(exit 3)"),
+	)
+})
+
+var _ = Describe("parseEnvsLegacy", func() {
+	envs := config.Envs{}
+	envs.AddEnv("VAR1", "VAL_1")
+	envs.AddEnv("VAR2", `"$VAR1"`)
+	envs.AddEnv("VAR3", "$(this-is-error)")
+	err := parseEnvsLegacy(envs)
+
+	It("should evaluate the value with shell echo", func() {
+		Expect(envs.Strings()).To(ContainElement("VAR2=VAL_1"))
+	})
+
+	It("should occur error when result is empty", func() {
+		Expect(err).To(HaveOccurred())
+		Expect(envs.Strings()).To(ContainElement("VAR3=$(this-is-error)"))
+	})
+})
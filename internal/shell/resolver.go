@@ -0,0 +1,172 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+const (
+	// providerExecutablePrefix is prefix of executable name of the external provider. e.g. envp-provider-vault
+	providerExecutablePrefix = "envp-provider-"
+	// providerAPIVersion is version of the protocol between envp and the external provider
+	providerAPIVersion = "envp.provider/v1"
+)
+
+// ValueResolver resolves the value of env var from the source of value-from
+type ValueResolver interface {
+	Resolve(ctx context.Context, req *ResolveRequest) (string, error)
+}
+
+// ResolveRequest is request to ValueResolver
+type ResolveRequest struct {
+	Profile     string       // name of the profile
+	Env         *config.Env  // env var to resolve. its ValueFrom is the source
+	Environment *Environment // environment that env vars evaluated so far are applied
+}
+
+// valueResolvers is ValueResolver for each kind of value-from
+var valueResolvers = map[string]ValueResolver{
+	config.ValueFromFile:     &fileResolver{},
+	config.ValueFromEnv:      &envResolver{},
+	config.ValueFromCommand:  &commandResolver{},
+	config.ValueFromProvider: &providerResolver{},
+}
+
+// resolveValueFrom resolves value of env var with ValueResolver of the kind of its value-from
+func resolveValueFrom(ctx context.Context, profile string, env *config.Env, environment *Environment) (string, error) {
+	if err := env.ValueFrom.Validate(env.Name); err != nil {
+		return "", err
+	}
+	resolver, ok := valueResolvers[env.ValueFrom.Kind()]
+	if !ok {
+		return "", fmt.Errorf("value-from %s is not supported", env.ValueFrom.Kind())
+	}
+	return resolver.Resolve(ctx, &ResolveRequest{
+		Profile:     profile,
+		Env:         env,
+		Environment: environment,
+	})
+}
+
+// fileResolver reads the value from file. trailing and leading spaces are trimmed.
+// file must not be accessible by group and others since it is supposed to have credentials
+type fileResolver struct{}
+
+// Resolve reads the file of value-from
+func (r *fileResolver) Resolve(ctx context.Context, req *ResolveRequest) (string, error) {
+	// expand ~ and env vars in the path. command substitution is not allowed
+	x := &expander{
+		lookup: req.Environment.Lookup,
+		command: func(script string) (string, error) {
+			return "", fmt.Errorf("command substitution is not allowed in the file path")
+		},
+	}
+	path, err := x.expand(req.Env.ValueFrom.File)
+	if err != nil {
+		return "", err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("file %s is accessible by group or others (%v). it should be 0600 or 0400", path, fi.Mode().Perm())
+	}
+
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// envResolver copies the value of other env var in the parent environment
+type envResolver struct{}
+
+// Resolve copies the value of env var of value-from
+func (r *envResolver) Resolve(ctx context.Context, req *ResolveRequest) (string, error) {
+	name := req.Env.ValueFrom.Env
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", fmt.Errorf("env var %s is not set in the parent environment", name)
+	}
+	return value, nil
+}
+
+// commandResolver runs the command of value-from without shell and use its output as value
+type commandResolver struct{}
+
+// Resolve runs the command of value-from
+func (r *commandResolver) Resolve(ctx context.Context, req *ResolveRequest) (string, error) {
+	argv := req.Env.ValueFrom.Command
+	binary, err := exec.LookPath(argv[0])
+	if err != nil {
+		return "", err
+	}
+	cmd := commandContext(ctx, binary, argv[1:]...)
+	cmd.Env = req.Environment.Strings()
+	return runCommand(ctx, cmd, argv[0])
+}
+
+// ProviderRequest is request that is written into stdin of the external provider as JSON
+type ProviderRequest struct {
+	APIVersion string            `json:"apiVersion"`
+	Profile    string            `json:"profile"`
+	Name       string            `json:"name"`
+	Params     map[string]string `json:"params,omitempty"`
+}
+
+// ProviderResponse is response that the external provider writes into stdout as JSON
+type ProviderResponse struct {
+	Value string `json:"value"`
+	Error string `json:"error,omitempty"`
+}
+
+// providerResolver runs external provider executable `envp-provider-<name>` in the PATH.
+// ProviderRequest is passed via stdin and ProviderResponse is read from stdout.
+// so that team can add the secret backend without changing envp
+type providerResolver struct{}
+
+// Resolve runs external provider of value-from
+func (r *providerResolver) Resolve(ctx context.Context, req *ResolveRequest) (string, error) {
+	name := providerExecutablePrefix + req.Env.ValueFrom.Provider
+	binary, err := exec.LookPath(name)
+	if err != nil {
+		return "", err
+	}
+
+	in, err := json.Marshal(&ProviderRequest{
+		APIVersion: providerAPIVersion,
+		Profile:    req.Profile,
+		Name:       req.Env.Name,
+		Params:     req.Env.ValueFrom.Params,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	cmd := commandContext(ctx, binary)
+	cmd.Env = req.Environment.Strings()
+	cmd.Stdin = bytes.NewReader(in)
+	out, err := runCommand(ctx, cmd, name)
+	if err != nil {
+		return "", err
+	}
+
+	var res ProviderResponse
+	if err := json.Unmarshal([]byte(out), &res); err != nil {
+		return "", fmt.Errorf("invalid response of provider %s: %w", name, err)
+	}
+	if res.Error != "" {
+		return "", fmt.Errorf("provider %s: %s", name, res.Error)
+	}
+	return res.Value, nil
+}
@@ -0,0 +1,129 @@
+package shell
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("value-from", Ordered, func() {
+
+	var dir string
+
+	BeforeAll(func() {
+		dir = GinkgoT().TempDir()
+		Expect(os.WriteFile(filepath.Join(dir, "secret"), []byte("file-secret\n"), 0600)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "public"), []byte("public"), 0644)).To(Succeed())
+
+		// provider that echoes the request back as the value
+		provider := `#!/bin/sh
+req=$(cat)
+case "$req" in
+  *'"fail":"true"'*) echo '{"error":"failed by request"}' ;;
+  *) printf '{"value":"%s"}' "$(printf '%s' "$req" | sed 's/"/\\"/g')" ;;
+esac
+`
+		Expect(os.WriteFile(filepath.Join(dir, "envp-provider-echo"), []byte(provider), 0755)).To(Succeed())
+		GinkgoT().Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+		GinkgoT().Setenv("ENVP_TEST_VALUE_FROM", "env-value")
+	})
+
+	evaluate := func(envs config.Envs) error {
+		return (&envEvaluator{profile: "my-profile"}).evaluate(envs)
+	}
+
+	It("should read the value from file", func() {
+		envs := config.Envs{
+			&config.Env{Name: "DIR", Value: dir},
+			&config.Env{Name: "SECRET", ValueFrom: &config.ValueFrom{File: "$DIR/secret"}},
+		}
+		Expect(evaluate(envs)).To(Succeed())
+		Expect(envs[1].Value).To(Equal("file-secret"))
+	})
+
+	It("should not read the file that is accessible by others", func() {
+		envs := config.Envs{
+			&config.Env{Name: "SECRET", ValueFrom: &config.ValueFrom{File: filepath.Join(dir, "public")}},
+		}
+		err := evaluate(envs)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("accessible by group or others"))
+	})
+
+	It("should not run command substitution in the file path", func() {
+		envs := config.Envs{
+			&config.Env{Name: "SECRET", ValueFrom: &config.ValueFrom{File: "$(echo secret)"}},
+		}
+		Expect(evaluate(envs)).NotTo(Succeed())
+	})
+
+	It("should copy the value of env var of parent environment", func() {
+		envs := config.Envs{
+			&config.Env{Name: "COPIED", ValueFrom: &config.ValueFrom{Env: "ENVP_TEST_VALUE_FROM"}},
+			&config.Env{Name: "MISSING", ValueFrom: &config.ValueFrom{Env: "ENVP_TEST_NOT_EXISTING"}},
+		}
+		err := evaluate(envs)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("MISSING"))
+		Expect(envs[0].Value).To(Equal("env-value"))
+	})
+
+	It("should run the command without shell", func() {
+		envs := config.Envs{
+			&config.Env{Name: "OUTPUT", ValueFrom: &config.ValueFrom{Command: []string{"printenv", "ENVP_TEST_VALUE_FROM"}}},
+			&config.Env{Name: "LITERAL", ValueFrom: &config.ValueFrom{Command: []string{"echo", "$ENVP_TEST_VALUE_FROM"}}},
+		}
+		Expect(evaluate(envs)).To(Succeed())
+		Expect(envs[0].Value).To(Equal("env-value"))
+		Expect(envs[1].Value).To(Equal("$ENVP_TEST_VALUE_FROM"))
+	})
+
+	It("should run the provider with request via stdin", func() {
+		envs := config.Envs{
+			&config.Env{Name: "TOKEN", ValueFrom: &config.ValueFrom{Provider: "echo", Params: map[string]string{"path": "secret/token"}}},
+		}
+		Expect(evaluate(envs)).To(Succeed())
+		Expect(envs[0].Value).To(Equal(`{"apiVersion":"envp.provider/v1","profile":"my-profile","name":"TOKEN","params":{"path":"secret/token"}}`))
+	})
+
+	It("should return the error of provider", func() {
+		envs := config.Envs{
+			&config.Env{Name: "TOKEN", ValueFrom: &config.ValueFrom{Provider: "echo", Params: map[string]string{"fail": "true"}}},
+		}
+		err := evaluate(envs)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("failed by request"))
+	})
+
+	It("should return the error if provider does not exist", func() {
+		envs := config.Envs{
+			&config.Env{Name: "TOKEN", ValueFrom: &config.ValueFrom{Provider: "not-existing"}},
+		}
+		Expect(evaluate(envs)).NotTo(Succeed())
+	})
+
+	It("should not run the provider that is out of the PATH", func() {
+		// envp-provider-../<dir>/envp-provider-echo would be relative path of existing executable
+		envs := config.Envs{
+			&config.Env{Name: "TOKEN", ValueFrom: &config.ValueFrom{Provider: "../" + filepath.Base(dir) + "/envp-provider-echo"}},
+		}
+		err := evaluate(envs)
+		var providerErr *config.ProviderNameInvalidError
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &providerErr)).To(BeTrue())
+	})
+
+	It("should not allow multiple sources", func() {
+		envs := config.Envs{
+			&config.Env{Name: "TOKEN", ValueFrom: &config.ValueFrom{Env: "HOME", File: "~/.token"}},
+		}
+		err := evaluate(envs)
+		var invalidErr *config.ValueFromInvalidError
+		Expect(err).To(HaveOccurred())
+		Expect(errors.As(err, &invalidErr)).To(BeTrue())
+	})
+})
@@ -0,0 +1,26 @@
+package shell
+
+import (
+	"context"
+	"os/exec"
+)
+
+// scriptEnvVarKey is env var to pass the script to the shell.
+// script such as command substitution and init-script can contain secrets. so it is passed with env var instead of argv,
+// since argv of process is visible to other users in `ps` output.
+const scriptEnvVarKey = "ENVP_SCRIPT"
+
+// scriptArgs is argv of /bin/sh to run the script in the scriptEnvVarKey.
+// env var is unset before running the script so that child processes of the script don't inherit it
+var scriptArgs = []string{"-c", `__envp_script="$` + scriptEnvVarKey + `"; unset ` + scriptEnvVarKey + `; eval "$__envp_script"`}
+
+// commandContext creates *exec.Cmd. every process of shell package is created by it.
+// it is variable to make it possible to inspect the spawned processes in the test
+var commandContext = exec.CommandContext
+
+// scriptCommand creates *exec.Cmd that runs script with /bin/sh. script is passed with env var and never appears on the argv.
+func scriptCommand(ctx context.Context, script string, environ []string) *exec.Cmd {
+	cmd := commandContext(ctx, "/bin/sh", scriptArgs...)
+	cmd.Env = append(append([]string{}, environ...), scriptEnvVarKey+"="+script)
+	return cmd
+}
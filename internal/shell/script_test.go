@@ -0,0 +1,101 @@
+package shell
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+var _ = Describe("argv of spawned processes", Ordered, func() {
+
+	const secret = "s3cr3t-meow-value"
+
+	var (
+		mu    sync.Mutex
+		argvs [][]string
+	)
+
+	// record argv of every process that is spawned by shell package
+	BeforeAll(func() {
+		original := commandContext
+		commandContext = func(ctx context.Context, name string, arg ...string) *exec.Cmd {
+			mu.Lock()
+			argvs = append(argvs, append([]string{name}, arg...))
+			mu.Unlock()
+			return original(ctx, name, arg...)
+		}
+		DeferCleanup(func() {
+			commandContext = original
+		})
+	})
+
+	BeforeEach(func() {
+		argvs = nil
+	})
+
+	expectNoSecretInArgv := func() {
+		Expect(argvs).NotTo(BeEmpty())
+		for _, argv := range argvs {
+			Expect(strings.Join(argv, " ")).NotTo(ContainSubstring(secret))
+		}
+	}
+
+	It("should not expose the values of command substitution", func() {
+		envs := config.Envs{}
+		envs.AddEnv("SECRET", secret)
+		envs.AddEnv("SUBST", "$(echo "+secret+")")
+		envs.AddEnv("REF", "$(echo $SECRET)")
+		Expect(parseEnvs(envs)).To(Succeed())
+		Expect(envs.Strings()).To(ContainElement("REF=" + secret))
+		Expect(envs.Strings()).To(ContainElement("SUBST=" + secret))
+		expectNoSecretInArgv()
+	})
+
+	It("should not expose the values of legacy expansion", func() {
+		envs := config.Envs{}
+		envs.AddEnv("SECRET", secret)
+		Expect(parseEnvsLegacy(envs)).To(Succeed())
+		Expect(envs.Strings()).To(ContainElement("SECRET=" + secret))
+		expectNoSecretInArgv()
+	})
+
+	It("should not expose the init-script", func() {
+		var stdout bytes.Buffer
+		sc := NewShellCommand()
+		sc.Stdout = &stdout
+		profile := config.NamedProfile{
+			Name:    "my-profile",
+			Profile: config.NewProfile(),
+		}
+		profile.InitScript = "echo " + secret
+		Expect(sc.executeInitScript(&profile)).To(Succeed())
+		Expect(stdout.String()).To(ContainSubstring(secret))
+		expectNoSecretInArgv()
+	})
+
+	It("should not expose the values on the command line of actual process", func() {
+		if _, err := os.Stat("/proc/self/cmdline"); err != nil {
+			Skip("/proc is not available")
+		}
+		envs := config.Envs{}
+		envs.AddEnv("CMDLINE", "$(tr '\\0' ' ' < /proc/$$/cmdline) "+secret)
+		Expect(parseEnvs(envs)).To(Succeed())
+		cmdline := strings.TrimSuffix(envs[0].Value, " "+secret)
+		Expect(cmdline).To(HavePrefix("/bin/sh"))
+		Expect(cmdline).NotTo(ContainSubstring(secret))
+	})
+
+	It("should not pass the script to the child processes of the script", func() {
+		envs := config.Envs{}
+		envs.AddEnv("INHERITED", "$(/bin/sh -c 'echo ${"+scriptEnvVarKey+":-none}')")
+		Expect(parseEnvs(envs)).To(Succeed())
+		Expect(envs[0].Value).To(Equal("none"))
+	})
+})
@@ -1,12 +1,12 @@
 package shell
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
-	"strings"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/sunggun-yu/envp/internal/config"
@@ -23,6 +23,20 @@ type ShellCommand struct {
 	Stdin  io.Reader
 	Stdout io.Writer
 	Stderr io.Writer
+	// LegacyExpansion evaluates env values with shell echo instead of native expander
+	LegacyExpansion bool
+	// ValueTimeout is timeout of evaluating each env value. DefaultValueTimeout is used if it is zero
+	ValueTimeout time.Duration
+	// TotalTimeout is timeout of evaluating all the env values. DefaultTotalTimeout is used if it is zero
+	TotalTimeout time.Duration
+	// Cache caches the evaluated values of env vars that have cache ttl. caching is disabled if it is nil
+	Cache ValueCache
+	// Overrides is env that is given at runtime. it wins over the env of profile
+	Overrides config.Envs
+	// Secrets decrypts the encrypted env values. encrypted values cannot be used if it is nil
+	Secrets Decrypter
+	// DebugEnv prints the environment variables of child process with the layer that each value came from into Stderr
+	DebugEnv bool
 }
 
 // NewShellCommand create ShellCommand with os stdin, stdout, and stderr as default
@@ -34,6 +48,18 @@ func NewShellCommand() *ShellCommand {
 	}
 }
 
+// evaluator creates envEvaluator of the profile with the settings of ShellCommand
+func (s *ShellCommand) evaluator(profile string) *envEvaluator {
+	return &envEvaluator{
+		profile:      profile,
+		cache:        s.Cache,
+		legacy:       s.LegacyExpansion,
+		valueTimeout: s.ValueTimeout,
+		totalTimeout: s.TotalTimeout,
+		secrets:      s.Secrets,
+	}
+}
+
 // Execute executes given command
 func (s *ShellCommand) Execute(cmd []string, profile *config.NamedProfile, skipInitScript bool) error {
 	return s.execCommand(cmd[0], cmd, profile, skipInitScript)
@@ -75,18 +101,26 @@ func (s *ShellCommand) execCommand(argv0 string, argv []string, profile *config.
 		return err
 	}
 
-	// TODO: refactor and make this clear separation of parsing and command substitution
-	err = parseEnvs(profile.Env)
+	// evaluate the values of env
+	err = s.evaluator(profile.Name).evaluate(profile.Env)
+	if err != nil {
+		return err
+	}
+
+	environment, err := s.environment(profile)
 	if err != nil {
 		return err
 	}
+	if s.DebugEnv {
+		for _, d := range environment.Debug(false) {
+			s.Stderr.Write([]byte(fmt.Sprintln(d)))
+		}
+	}
 
 	// create command for binary
-	cmd := s.createCommand(&profile.Env, binary)
+	cmd := s.createCommand(environment.Strings(), binary)
 	// set args
 	cmd.Args = argv
-	// set ENVP_PROFILE
-	cmd.Env = appendEnvpProfile(cmd.Env, profile.Name)
 
 	if !skipInitScript {
 		// run init-script
@@ -103,6 +137,19 @@ func (s *ShellCommand) execCommand(argv0 string, argv []string, profile *config.
 	return nil
 }
 
+// environment builds Environment for the profile with layers of parent, profile, runtime overrides and ENVP_* metadata
+func (s *ShellCommand) environment(profile *config.NamedProfile) (*Environment, error) {
+	environment, err := profileEnvironment(profile.Env)
+	if err != nil {
+		return nil, err
+	}
+	if err := environment.Apply(LayerRuntime, s.Overrides); err != nil {
+		return nil, err
+	}
+	environment.setEnvpProfile(profile.Name)
+	return environment, nil
+}
+
 // executeInitScript executes the initial script for the shell
 func (s *ShellCommand) executeInitScript(profile *config.NamedProfile) error {
 	// Return if profile or init-script is empty
@@ -110,9 +157,14 @@ func (s *ShellCommand) executeInitScript(profile *config.NamedProfile) error {
 		return nil
 	}
 
+	environment, err := s.environment(profile)
+	if err != nil {
+		return err
+	}
+
 	// loop and run init script in order
 	for _, initScript := range profile.InitScripts() {
-		cmd := s.createCommand(&profile.Env, "/bin/sh", "-c", initScript)
+		cmd := s.createScriptCommand(environment.Strings(), initScript)
 		err := cmd.Run()
 		if err != nil {
 			return fmt.Errorf("init-script error: %w", err)
@@ -123,58 +175,25 @@ func (s *ShellCommand) executeInitScript(profile *config.NamedProfile) error {
 
 // createCommand creates an *exec.Cmd instance configured with the provided command, arguments,
 // environment variables, and associates Stdin, Stdout, and Stderr with the ShellCommand instance.
-func (s *ShellCommand) createCommand(envs *config.Envs, cmd string, arg ...string) *exec.Cmd {
+func (s *ShellCommand) createCommand(environ []string, cmd string, arg ...string) *exec.Cmd {
 
-	c := exec.Command(cmd, arg...)
+	c := commandContext(context.Background(), cmd, arg...)
 	c.Stdin = s.Stdin
 	c.Stdout = s.Stdout
 	c.Stderr = s.Stderr
-
-	// init command Env with os.Environ()
-	c.Env = os.Environ()
-	// append config Envs to command
-	c.Env = append(c.Env, envs.Strings()...)
+	c.Env = environ
 
 	return c
 }
 
-// parseEnvs parse Env values with shell echo
-func parseEnvs(envs config.Envs) (errs error) {
-	for _, e := range envs {
-		// parse env value with shell echo
-		cmd := exec.Command("/bin/sh", "-c", fmt.Sprintf("echo %s", e.Value))
-		// append envs to cmd that runs command substitution as well to support the case that reuse env var as ref with substitution
-		cmd.Env = os.Environ()
-		cmd.Env = append(cmd.Env, envs.Strings()...)
-
-		// it never occurs error since it is processed with shell echo.
-		// so that, it will not exit 1 even command substitution has error. and just print out empty line when it errors
-		output, _ := cmd.Output()
-		// trim new lines from result
-		result := strings.TrimRight(string(output), "\r\n")
-		// use os.ExpandEnv to replace all the ${var} or $var in the string according to the values of the current environment variables.
-		// so that $HOME will be replaced to current user's abs home dir
-		result = os.ExpandEnv(result)
-
-		if len(e.Value) > 0 && len(result) == 0 {
-			// join errors
-			errs = errors.Join(errs, fmt.Errorf("[envp] error processing value of %s: %s", e.Name, e.Value))
-		} else {
-			e.Value = result
-		}
-	}
-	return errs
-}
+// createScriptCommand creates an *exec.Cmd instance that runs script with /bin/sh,
+// and associates Stdin, Stdout, and Stderr with the ShellCommand instance. script never appears on the argv.
+func (s *ShellCommand) createScriptCommand(environ []string, script string) *exec.Cmd {
 
-// appendEnvpProfile set ENVP_PROFILE env var to leverage profile info in the shell prompt, such as starship.
-func appendEnvpProfile(envs []string, profile string) []string {
-	// Check if the environment variable already has a value
-	if value, exists := os.LookupEnv(envpEnvVarKey); exists {
-		// Append the profile if it is already set
-		// conjunction with the previous value with ">" to indicate profiles are nested
-		envs = append(envs, fmt.Sprintf("%s=%s > %s", envpEnvVarKey, value, profile))
-	} else {
-		envs = append(envs, fmt.Sprintf("%s=%s", envpEnvVarKey, profile))
-	}
-	return envs
+	c := scriptCommand(context.Background(), script, environ)
+	c.Stdin = s.Stdin
+	c.Stdout = s.Stdout
+	c.Stderr = s.Stderr
+
+	return c
 }
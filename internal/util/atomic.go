@@ -0,0 +1,80 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// WriteFileAtomic writes data to the file atomically.
+// data is written into temp file in the same directory, synced to disk and renamed to the file,
+// so that the file is never empty or half-written even if the process crashes or disk is full.
+// symlink is resolved and the file that it points is replaced, so that the symlink of dotfile managers is kept
+func WriteFileAtomic(name string, data []byte, perm os.FileMode) (err error) {
+	name = resolveSymlink(name)
+	dir := filepath.Dir(name)
+	f, err := os.CreateTemp(dir, "."+filepath.Base(name)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	// remove temp file if anything is failed
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+
+	if err = f.Chmod(perm); err != nil {
+		return err
+	}
+	if _, err = f.Write(data); err != nil {
+		return err
+	}
+	if err = f.Sync(); err != nil {
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	if err = os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+	// sync directory to persist the rename
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
+
+// LockFile acquires exclusive advisory lock of the file with lock file `.<name>.lock` in the same directory. it blocks until the lock is acquired.
+// lock file is not removed since other process may wait for the lock of it. it returns the function that releases the lock
+func LockFile(name string) (unlock func() error, err error) {
+	f, err := os.OpenFile(LockFileName(name), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return func() error {
+		defer f.Close()
+		return unlockFile(f)
+	}, nil
+}
+
+// LockFileName returns name of the lock file of the file. lock file is next to the file that symlink points
+// so that the file is locked by same lock file whichever path it is referred by
+func LockFileName(name string) string {
+	name = resolveSymlink(name)
+	return filepath.Join(filepath.Dir(name), "."+filepath.Base(name)+".lock")
+}
+
+// resolveSymlink returns the path of the file that symlink points. name is returned as it is if it is not existing
+func resolveSymlink(name string) string {
+	if p, err := filepath.EvalSymlinks(name); err == nil {
+		return p
+	}
+	return name
+}
@@ -0,0 +1,81 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("WriteFileAtomic", func() {
+	It("should replace the file with the permission and leave no temp file", func() {
+		dir := GinkgoT().TempDir()
+		name := filepath.Join(dir, "config.yaml")
+		Expect(os.WriteFile(name, []byte("old"), 0644)).To(Succeed())
+
+		Expect(WriteFileAtomic(name, []byte("new"), 0600)).To(Succeed())
+
+		b, _ := os.ReadFile(name)
+		Expect(string(b)).To(Equal("new"))
+		fi, _ := os.Stat(name)
+		Expect(fi.Mode().Perm()).To(Equal(os.FileMode(0600)))
+		entries, _ := os.ReadDir(dir)
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should write the file that symlink points and keep the symlink", func() {
+		dir := GinkgoT().TempDir()
+		real := filepath.Join(dir, "dotfiles", "config.yaml")
+		Expect(os.MkdirAll(filepath.Dir(real), 0755)).To(Succeed())
+		Expect(os.WriteFile(real, []byte("old"), 0600)).To(Succeed())
+		link := filepath.Join(dir, "config.yaml")
+		Expect(os.Symlink(real, link)).To(Succeed())
+
+		Expect(WriteFileAtomic(link, []byte("new"), 0600)).To(Succeed())
+
+		fi, _ := os.Lstat(link)
+		Expect(fi.Mode() & os.ModeSymlink).NotTo(BeZero())
+		b, _ := os.ReadFile(real)
+		Expect(string(b)).To(Equal("new"))
+		Expect(LockFileName(link)).To(Equal(LockFileName(real)))
+	})
+
+	It("should keep the file when it is failed", func() {
+		dir := GinkgoT().TempDir()
+		name := filepath.Join(dir, "not-existing-dir", "config.yaml")
+		Expect(WriteFileAtomic(name, []byte("new"), 0600)).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("LockFile", func() {
+	It("should block until the lock is released", func() {
+		name := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+		unlock, err := LockFile(name)
+		Expect(err).NotTo(HaveOccurred())
+
+		var mu sync.Mutex
+		acquired := false
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			unlock, err := LockFile(name)
+			Expect(err).NotTo(HaveOccurred())
+			mu.Lock()
+			acquired = true
+			mu.Unlock()
+			unlock()
+		}()
+
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		Expect(acquired).To(BeFalse())
+		mu.Unlock()
+
+		Expect(unlock()).To(Succeed())
+		Eventually(done).Should(BeClosed())
+		Expect(LockFileName(name)).To(Equal(filepath.Join(filepath.Dir(name), ".config.yaml.lock")))
+	})
+})
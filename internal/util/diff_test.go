@@ -0,0 +1,16 @@
+package util
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Diff", func() {
+	It("should prefix removed and added lines", func() {
+		Expect(Diff("a\nb\nd", "a\nc\nd")).To(Equal("  a\n- b\n+ c\n  d\n"))
+	})
+
+	It("should have only added lines from empty string", func() {
+		Expect(Diff("", "a")).To(Equal("+ a\n"))
+	})
+})
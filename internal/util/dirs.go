@@ -0,0 +1,37 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// AppName is the name of the directories of envp in the base directories
+const AppName = "envp"
+
+// ConfigDir returns the directory of config file. it is envp in $XDG_CONFIG_HOME, or ~/.config/envp if it is not set
+func ConfigDir() (string, error) {
+	return xdgDir("XDG_CONFIG_HOME", ".config")
+}
+
+// StateDir returns the directory of state files such as trust store. it is envp in $XDG_STATE_HOME, or ~/.local/state/envp if it is not set
+func StateDir() (string, error) {
+	return xdgDir("XDG_STATE_HOME", filepath.Join(".local", "state"))
+}
+
+// CacheDir returns the directory of cache files. it is envp in $XDG_CACHE_HOME, or ~/.cache/envp if it is not set
+func CacheDir() (string, error) {
+	return xdgDir("XDG_CACHE_HOME", ".cache")
+}
+
+// xdgDir returns envp directory in the base directory of env var. relative path is ignored as XDG base directory spec says.
+// home is the default base directory relative to home dir
+func xdgDir(env, home string) (string, error) {
+	if base := os.Getenv(env); filepath.IsAbs(base) {
+		return filepath.Join(base, AppName), nil
+	}
+	userHome, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(userHome, home, AppName), nil
+}
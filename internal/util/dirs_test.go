@@ -0,0 +1,35 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("XDG directories", func() {
+	home, _ := os.UserHomeDir()
+
+	When("XDG env vars are set", func() {
+		It("should return envp directory in them", func() {
+			GinkgoT().Setenv("XDG_CONFIG_HOME", "/tmp/xdg/config")
+			GinkgoT().Setenv("XDG_STATE_HOME", "/tmp/xdg/state")
+			GinkgoT().Setenv("XDG_CACHE_HOME", "/tmp/xdg/cache")
+			Expect(ConfigDir()).To(Equal("/tmp/xdg/config/envp"))
+			Expect(StateDir()).To(Equal("/tmp/xdg/state/envp"))
+			Expect(CacheDir()).To(Equal("/tmp/xdg/cache/envp"))
+		})
+	})
+
+	When("XDG env vars are not set or relative", func() {
+		It("should return envp directory in the default of home dir", func() {
+			GinkgoT().Setenv("XDG_CONFIG_HOME", "")
+			GinkgoT().Setenv("XDG_STATE_HOME", "relative/state")
+			GinkgoT().Setenv("XDG_CACHE_HOME", "")
+			Expect(ConfigDir()).To(Equal(filepath.Join(home, ".config", "envp")))
+			Expect(StateDir()).To(Equal(filepath.Join(home, ".local", "state", "envp")))
+			Expect(CacheDir()).To(Equal(filepath.Join(home, ".cache", "envp")))
+		})
+	})
+})
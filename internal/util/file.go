@@ -2,6 +2,7 @@ package util
 
 import (
 	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -26,7 +27,7 @@ func ExpandHomeDir(f string) (string, error) {
 
 // EnsureConfigFilePath ensure the config file path.
 // it mkdir -p the file's directory if not exist
-// also returns abs path of file name if it starts from `~` or `$HOME`
+// also returns abs path of file name if it starts from `~` or `$HOME`, or is relative to current dir
 // path must be "dir" not "file"
 func EnsureConfigFilePath(path string) (string, error) {
 	// expand home dir
@@ -34,6 +35,10 @@ func EnsureConfigFilePath(path string) (string, error) {
 	if err != nil {
 		return path, err
 	}
+	// file given by --config flag or ENVP_CONFIG can be relative path
+	if f, err = filepath.Abs(f); err != nil {
+		return path, err
+	}
 	// ensure if file is existing
 	if _, err := os.Stat(f); err != nil {
 		if os.IsNotExist(err) {
@@ -92,6 +92,16 @@ var _ = Describe("EnsureConfigFilePath", func() {
 		})
 	})
 
+	When("set relative directory", func() {
+		It("should return abs path of it", func() {
+			GinkgoT().Chdir(GinkgoT().TempDir())
+			wd, _ := os.Getwd()
+			path, err := EnsureConfigFilePath("some-dir")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal(filepath.Join(wd, "some-dir")))
+		})
+	})
+
 	When("error occurred getting $HOME", func() {
 		// backup original home path to set it back after test
 		var originalHome string